@@ -0,0 +1,34 @@
+package activities
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// Activity is an activity authored by the relay
+type Activity struct {
+	ID        uuid.UUID
+	Type      string
+	Published time.Time
+	// Data is the serialized JSON-LD activity
+	Data []byte
+	// Deleted is when the activity was deleted, and is zero for live activities
+	Deleted time.Time
+	// Unlisted activities can be fetched by ID but are not listed, such as
+	// activities addressed to a single actor
+	Unlisted bool
+}
+
+// Store keeps the activities the relay has published
+type Store interface {
+	Put(activity Activity) bool
+	Get(id uuid.UUID) (Activity, bool)
+	// List returns up to limit listed activities starting at offset,
+	// newest first
+	List(offset, limit int) []Activity
+	// Count returns the number of listed activities
+	Count() int
+	// Delete marks an activity as deleted at the given time
+	Delete(id uuid.UUID, at time.Time) bool
+}
@@ -0,0 +1,117 @@
+package activities
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// MemoryStore is an in-memory activity store
+type MemoryStore struct {
+	// ordered holds activity IDs oldest first
+	ordered    []uuid.UUID
+	activities map[uuid.UUID]Activity
+	listed     int
+	max        int
+	sync.RWMutex
+}
+
+// NewMemoryStore returns a new MemoryStore instance which keeps up to max
+// activities, dropping the oldest ones first, listed or not. Dropped
+// activities are forgotten rather than deleted, so they cannot be served as
+// Tombstones. It keeps every activity when max is 0
+func NewMemoryStore(max int) *MemoryStore {
+	return &MemoryStore{
+		ordered:    make([]uuid.UUID, 0),
+		activities: make(map[uuid.UUID]Activity),
+		max:        max,
+	}
+}
+
+// Put stores an activity
+func (m *MemoryStore) Put(activity Activity) bool {
+	m.Lock()
+	defer m.Unlock()
+	if previous, ok := m.activities[activity.ID]; ok {
+		if !previous.Unlisted {
+			m.listed--
+		}
+	} else {
+		m.ordered = append(m.ordered, activity.ID)
+	}
+
+	m.activities[activity.ID] = activity
+	if !activity.Unlisted {
+		m.listed++
+	}
+
+	for m.max > 0 && len(m.ordered) > m.max {
+		m.dropOldest()
+	}
+	return true
+}
+
+// dropOldest removes the oldest activity
+func (m *MemoryStore) dropOldest() {
+	oldest := m.ordered[0]
+	m.ordered = m.ordered[1:]
+	if !m.activities[oldest].Unlisted {
+		m.listed--
+	}
+	delete(m.activities, oldest)
+}
+
+// Get returns the activity with the given ID
+func (m *MemoryStore) Get(id uuid.UUID) (Activity, bool) {
+	m.RLock()
+	defer m.RUnlock()
+	activity, ok := m.activities[id]
+	return activity, ok
+}
+
+// List returns up to limit listed activities starting at offset, newest
+// first. Offsets outside the store give an empty list
+func (m *MemoryStore) List(offset, limit int) []Activity {
+	m.RLock()
+	defer m.RUnlock()
+
+	if offset < 0 || limit <= 0 || offset >= m.listed {
+		return []Activity{}
+	}
+
+	list := make([]Activity, 0, limit)
+	for i := len(m.ordered) - 1; i >= 0 && len(list) < limit; i-- {
+		activity := m.activities[m.ordered[i]]
+		if activity.Unlisted {
+			continue
+		}
+		if offset > 0 {
+			offset--
+			continue
+		}
+		list = append(list, activity)
+	}
+	return list
+}
+
+// Count returns the number of listed activities
+func (m *MemoryStore) Count() int {
+	m.RLock()
+	defer m.RUnlock()
+	return m.listed
+}
+
+// Delete marks the activity with the given ID as deleted and reports
+// whether it exists
+func (m *MemoryStore) Delete(id uuid.UUID, at time.Time) bool {
+	m.Lock()
+	defer m.Unlock()
+	activity, ok := m.activities[id]
+	if !ok {
+		return false
+	}
+	activity.Deleted = at
+	m.activities[id] = activity
+	return true
+}
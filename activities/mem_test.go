@@ -0,0 +1,105 @@
+package activities
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+func TestMemoryStoreList(t *testing.T) {
+	t.Parallel()
+
+	store := NewMemoryStore(0)
+	ids := make([]uuid.UUID, 3)
+	for i := range ids {
+		id, err := uuid.NewV4()
+		if err != nil {
+			t.Fatalf("error generating activity id: %v", err)
+		}
+		ids[i] = id
+		store.Put(Activity{ID: id, Type: "Accept"})
+	}
+
+	if store.Count() != 3 {
+		t.Errorf("expected 3 activities got %d", store.Count())
+	}
+
+	list := store.List(0, 2)
+	if len(list) != 2 || list[0].ID != ids[2] || list[1].ID != ids[1] {
+		t.Errorf("expected newest two activities first got %v", list)
+	}
+
+	list = store.List(2, 2)
+	if len(list) != 1 || list[0].ID != ids[0] {
+		t.Errorf("expected oldest activity on the last page got %v", list)
+	}
+
+	for _, offset := range []int{3, -16, 1 << 62} {
+		if list = store.List(offset, 2); len(list) != 0 {
+			t.Errorf("expected offset %d to give no activities got %v", offset, list)
+		}
+	}
+
+	if _, ok := store.Get(ids[1]); !ok {
+		t.Errorf("expected activity %s to be found", ids[1])
+	}
+}
+
+func TestMemoryStoreDelete(t *testing.T) {
+	t.Parallel()
+
+	store := NewMemoryStore(0)
+	id, err := uuid.NewV4()
+	if err != nil {
+		t.Fatalf("error generating activity id: %v", err)
+	}
+
+	if store.Delete(id, time.Now()) {
+		t.Errorf("expected deleting an unknown activity to fail")
+	}
+
+	store.Put(Activity{ID: id, Type: "Announce"})
+	if !store.Delete(id, time.Now()) {
+		t.Errorf("expected activity to be deleted")
+	}
+
+	activity, ok := store.Get(id)
+	if !ok || activity.Deleted.IsZero() {
+		t.Errorf("expected deleted activity to be kept with a deletion time")
+	}
+}
+
+func TestMemoryStoreLimits(t *testing.T) {
+	t.Parallel()
+
+	store := NewMemoryStore(2)
+	ids := make([]uuid.UUID, 3)
+	for i := range ids {
+		id, err := uuid.NewV4()
+		if err != nil {
+			t.Fatalf("error generating activity id: %v", err)
+		}
+		ids[i] = id
+	}
+
+	store.Put(Activity{ID: ids[0], Type: "Update"})
+	store.Put(Activity{ID: ids[1], Type: "Accept", Unlisted: true})
+	if store.Count() != 1 {
+		t.Errorf("expected unlisted activities not to be counted got %d", store.Count())
+	}
+	if list := store.List(0, 2); len(list) != 1 || list[0].ID != ids[0] {
+		t.Errorf("expected only the listed activity got %v", list)
+	}
+	if _, ok := store.Get(ids[1]); !ok {
+		t.Errorf("expected the unlisted activity to be found by id")
+	}
+
+	store.Put(Activity{ID: ids[2], Type: "Update"})
+	if _, ok := store.Get(ids[0]); ok {
+		t.Errorf("expected the oldest activity to be dropped")
+	}
+	if list := store.List(0, 2); len(list) != 1 || list[0].ID != ids[2] {
+		t.Errorf("expected the newest listed activity got %v", list)
+	}
+}
@@ -2,21 +2,144 @@ package main
 
 import (
 	"fmt"
+	"net/url"
+	"regexp"
+	"time"
 
 	"github.com/BurntSushi/toml"
+	"github.com/Koshroy/turnover/controllers"
+	"github.com/Koshroy/turnover/keystore"
 )
 
-//ServerConfig defines config options for running the server
+const defaultKeyGracePeriod = 7 * 24 * time.Hour
+const defaultDedupWindow = 24 * time.Hour
+const defaultFetchTimeout = 10 * time.Second
+const defaultFetchCacheTTL = 10 * time.Minute
+const defaultFetchCacheSize = 1000
+const defaultFetchMaxSize = 1 << 20 // 1 MB
+const defaultWorkers = 4
+
+// usernamePattern limits usernames to characters which need no escaping
+// in acct: URIs
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// ServerConfig defines config options for running the server
 type ServerConfig struct {
-	Scheme     string
-	Hostname   string
-	PublicKey  string `toml:"public_key"`
-	PrivateKey string `toml:"private_key"`
+	Scheme    string
+	Hostname  string
+	PublicKey string `toml:"public_key"`
+	// The private key is given as exactly one of a file path, inline PEM
+	// or the name of an environment variable holding the PEM
+	PrivateKey    string `toml:"private_key"`
+	PrivateKeyPEM string `toml:"private_key_pem"`
+	PrivateKeyEnv string `toml:"private_key_env"`
+	// Ed25519PrivateKey is an optional PKCS#8 Ed25519 key published as a
+	// Multikey, given as a file path or through an environment variable
+	Ed25519PrivateKey    string `toml:"ed25519_private_key"`
+	Ed25519PrivateKeyEnv string `toml:"ed25519_private_key_env"`
+	// KeyGracePeriod is how long rotated out keys stay advertised,
+	// as a duration string such as "168h". Rotated out keys are only kept
+	// in memory, so a restart ends their grace period
+	KeyGracePeriod string `toml:"key_grace_period"`
+	// Metrics serves expvar counters at /debug/vars
+	Metrics bool
+}
+
+// RelayConfig defines config options for relaying
+type RelayConfig struct {
+	// Whitelist limits subscriptions to the listed instances, and
+	// anyone may subscribe when it is empty
+	Whitelist []string
+	// HideCollections hides the members of the followers collection, only
+	// exposing how many there are
+	HideCollections bool `toml:"hide_collections"`
+	// CollectionPageSize is the number of items on a collection page
+	CollectionPageSize int `toml:"collection_page_size"`
+	// RemoteContexts are URL prefixes of JSON-LD contexts which may be
+	// fetched when they are not bundled. Nothing is fetched when empty
+	RemoteContexts []string `toml:"remote_contexts"`
+	// Policies overrides how activity types are handled, mapping type
+	// names such as Announce to relay, ignore or local
+	Policies map[string]string
+	// DedupWindow is how long activity IDs are remembered so redelivered
+	// activities are only relayed once, as a duration string such as "24h"
+	DedupWindow string `toml:"dedup_window"`
+	// DedupFile persists the remembered activity IDs across restarts.
+	// They are only kept in memory when empty
+	DedupFile string `toml:"dedup_file"`
+	// Workers is how many deliveries run at once, so a slow or dead
+	// subscriber inbox does not hold up the others
+	Workers int
+	// Limits bound the resources spent on a single inbox request
+	Limits LimitsConfig
+	// Fetch configures how objects and actors are fetched
+	Fetch FetchConfig
 }
 
-//Config is the config object
+// FetchConfig defines how referenced objects and actors are fetched with
+// signed GET requests. Options left at zero use their defaults
+type FetchConfig struct {
+	// Timeout bounds each fetch, as a duration string such as "10s". Fetches
+	// happen while an inbox request is handled, so this also bounds the
+	// latency an uncached object adds to it
+	Timeout string
+	// CacheTTL is how long fetched documents are cached
+	CacheTTL string `toml:"cache_ttl"`
+	// CacheSize is how many fetched documents are cached
+	CacheSize int `toml:"cache_size"`
+	// MaxSize is the largest document fetched, in bytes
+	MaxSize int64 `toml:"max_size"`
+}
+
+// FetchOptions are the parsed FetchConfig options
+type FetchOptions struct {
+	Timeout   time.Duration
+	CacheTTL  time.Duration
+	CacheSize int
+	MaxSize   int64
+}
+
+// LimitsConfig defines the limits on inbox requests. Limits left at zero
+// use their defaults
+type LimitsConfig struct {
+	// MaxBodySize is the largest request body accepted, in bytes
+	MaxBodySize int64 `toml:"max_body_size"`
+	// MaxDepth is how deeply JSON objects and arrays may be nested
+	MaxDepth int `toml:"max_depth"`
+	// MaxNodes is how many JSON-LD nodes an activity may hold
+	MaxNodes int `toml:"max_nodes"`
+	// MaxExpansionTime is how long JSON-LD expansion of an activity may
+	// take, as a duration string such as "5s"
+	MaxExpansionTime string `toml:"max_expansion_time"`
+}
+
+// ActorConfig defines the profile of the relay actor
+type ActorConfig struct {
+	Name string
+	// Summary is HTML
+	Summary           string
+	PreferredUsername string `toml:"preferred_username"`
+	// Icon and Image are URLs of the avatar and header images
+	Icon                      string
+	Image                     string
+	ManuallyApprovesFollowers bool `toml:"manually_approves_followers"`
+	Discoverable              bool
+	// Attachments are published as PropertyValue fields, such as a
+	// contact address or a link to the rules
+	Attachments []ActorAttachment `toml:"attachment"`
+}
+
+// ActorAttachment is a name and HTML value shown on the actor profile
+type ActorAttachment struct {
+	Name  string
+	Value string
+}
+
+// Config is the config object
 type Config struct {
 	Server ServerConfig
+	Relay  RelayConfig
+	Actor  ActorConfig
 }
 
 // LoadConfig loads a config at configPath
@@ -46,17 +169,236 @@ func ValidateConfig(conf Config) error {
 		return fmt.Errorf("no hostname given")
 	}
 
-	if conf.Server.PublicKey == "" {
-		return fmt.Errorf("no public key path given")
+	err := conf.Server.PrivateKeySource().Validate()
+	if err == keystore.ErrNoKeySource {
+		return fmt.Errorf("no private key given")
+	}
+	if err != nil {
+		return fmt.Errorf("invalid private key config: %v", err)
 	}
 
-	if conf.Server.PrivateKey == "" {
-		return fmt.Errorf("no private key path given")
+	edKeySrc := conf.Server.Ed25519PrivateKeySource()
+	if !edKeySrc.IsZero() {
+		err = edKeySrc.Validate()
+		if err != nil {
+			return fmt.Errorf("invalid Ed25519 private key config: %v", err)
+		}
 	}
 
 	if conf.Server.Scheme == "" {
 		return fmt.Errorf("no scheme given")
 	}
 
+	if conf.Relay.CollectionPageSize < 0 {
+		return fmt.Errorf("collection page size cannot be negative")
+	}
+
+	if conf.Relay.Workers < 0 {
+		return fmt.Errorf("worker count cannot be negative")
+	}
+
+	_, err = conf.Server.KeyGrace()
+	if err != nil {
+		return err
+	}
+
+	_, err = conf.Relay.DedupDuration()
+	if err != nil {
+		return err
+	}
+
+	_, err = conf.Relay.Limits.Limits()
+	if err != nil {
+		return err
+	}
+
+	_, err = conf.Relay.Fetch.Options()
+	if err != nil {
+		return err
+	}
+
+	_, err = controllers.ParsePolicies(conf.Relay.Policies)
+	if err != nil {
+		return fmt.Errorf("invalid relay policies: %v", err)
+	}
+
+	return conf.Actor.validate()
+}
+
+func (a ActorConfig) validate() error {
+	if a.PreferredUsername != "" && !usernamePattern.MatchString(a.PreferredUsername) {
+		return fmt.Errorf("invalid preferred username %q", a.PreferredUsername)
+	}
+
+	for _, imageURL := range []string{a.Icon, a.Image} {
+		if imageURL == "" {
+			continue
+		}
+
+		u, err := url.Parse(imageURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid actor image URL %q", imageURL)
+		}
+	}
+
+	for _, attachment := range a.Attachments {
+		if attachment.Name == "" {
+			return fmt.Errorf("actor attachment has no name")
+		}
+	}
+
 	return nil
 }
+
+// Profile returns the relay actor profile
+func (a ActorConfig) Profile() controllers.Profile {
+	fields := make([]controllers.ProfileField, 0, len(a.Attachments))
+	for _, attachment := range a.Attachments {
+		fields = append(fields, controllers.ProfileField{
+			Name:  attachment.Name,
+			Value: attachment.Value,
+		})
+	}
+
+	return controllers.Profile{
+		Name:                      a.Name,
+		Summary:                   a.Summary,
+		PreferredUsername:         a.PreferredUsername,
+		Icon:                      a.Icon,
+		Image:                     a.Image,
+		ManuallyApprovesFollowers: a.ManuallyApprovesFollowers,
+		Discoverable:              a.Discoverable,
+		Attachments:               fields,
+	}
+}
+
+// PrivateKeySource returns where the RSA private key is loaded from
+func (s ServerConfig) PrivateKeySource() keystore.Source {
+	return keystore.Source{
+		PEM:  s.PrivateKeyPEM,
+		Env:  s.PrivateKeyEnv,
+		Path: s.PrivateKey,
+	}
+}
+
+// PublicKeySource returns where the RSA public key is loaded from, which
+// is empty when the public key should be derived from the private key
+func (s ServerConfig) PublicKeySource() keystore.Source {
+	return keystore.Source{Path: s.PublicKey}
+}
+
+// Ed25519PrivateKeySource returns where the Ed25519 private key is loaded from
+func (s ServerConfig) Ed25519PrivateKeySource() keystore.Source {
+	return keystore.Source{
+		Env:  s.Ed25519PrivateKeyEnv,
+		Path: s.Ed25519PrivateKey,
+	}
+}
+
+// KeyGrace returns the parsed key grace period, defaulting to a week
+func (s ServerConfig) KeyGrace() (time.Duration, error) {
+	if s.KeyGracePeriod == "" {
+		return defaultKeyGracePeriod, nil
+	}
+
+	grace, err := time.ParseDuration(s.KeyGracePeriod)
+	if err != nil {
+		return 0, fmt.Errorf("invalid key grace period %q: %v", s.KeyGracePeriod, err)
+	}
+
+	return grace, nil
+}
+
+// DedupDuration returns how long activity IDs are remembered
+func (r RelayConfig) DedupDuration() (time.Duration, error) {
+	if r.DedupWindow == "" {
+		return defaultDedupWindow, nil
+	}
+
+	window, err := time.ParseDuration(r.DedupWindow)
+	if err != nil {
+		return 0, fmt.Errorf("invalid dedup window %q: %v", r.DedupWindow, err)
+	}
+	if window <= 0 {
+		return 0, fmt.Errorf("dedup window %q must be positive", r.DedupWindow)
+	}
+
+	return window, nil
+}
+
+// WorkerCount returns how many delivery workers to run
+func (r RelayConfig) WorkerCount() int {
+	if r.Workers == 0 {
+		return defaultWorkers
+	}
+	return r.Workers
+}
+
+// Limits returns the inbox limits, with defaults for the ones left unset
+func (l LimitsConfig) Limits() (controllers.Limits, error) {
+	limits := controllers.DefaultLimits()
+
+	if l.MaxBodySize < 0 || l.MaxDepth < 0 || l.MaxNodes < 0 {
+		return limits, fmt.Errorf("limits cannot be negative")
+	}
+	if l.MaxBodySize > 0 {
+		limits.BodySize = l.MaxBodySize
+	}
+	if l.MaxDepth > 0 {
+		limits.Depth = l.MaxDepth
+	}
+	if l.MaxNodes > 0 {
+		limits.Nodes = l.MaxNodes
+	}
+
+	if l.MaxExpansionTime != "" {
+		expansionTime, err := time.ParseDuration(l.MaxExpansionTime)
+		if err != nil {
+			return limits, fmt.Errorf("invalid max expansion time %q: %v", l.MaxExpansionTime, err)
+		}
+		if expansionTime <= 0 {
+			return limits, fmt.Errorf("max expansion time %q must be positive", l.MaxExpansionTime)
+		}
+		limits.ExpansionTime = expansionTime
+	}
+
+	return limits, nil
+}
+
+// Options returns the fetch options, with defaults for the ones left unset
+func (f FetchConfig) Options() (FetchOptions, error) {
+	opts := FetchOptions{
+		Timeout:   defaultFetchTimeout,
+		CacheTTL:  defaultFetchCacheTTL,
+		CacheSize: defaultFetchCacheSize,
+		MaxSize:   defaultFetchMaxSize,
+	}
+
+	if f.CacheSize < 0 || f.MaxSize < 0 {
+		return opts, fmt.Errorf("fetch cache size and max size cannot be negative")
+	}
+	if f.CacheSize > 0 {
+		opts.CacheSize = f.CacheSize
+	}
+	if f.MaxSize > 0 {
+		opts.MaxSize = f.MaxSize
+	}
+
+	if f.Timeout != "" {
+		timeout, err := time.ParseDuration(f.Timeout)
+		if err != nil || timeout <= 0 {
+			return opts, fmt.Errorf("invalid fetch timeout %q", f.Timeout)
+		}
+		opts.Timeout = timeout
+	}
+
+	if f.CacheTTL != "" {
+		ttl, err := time.ParseDuration(f.CacheTTL)
+		if err != nil || ttl <= 0 {
+			return opts, fmt.Errorf("invalid fetch cache ttl %q", f.CacheTTL)
+		}
+		opts.CacheTTL = ttl
+	}
+
+	return opts, nil
+}
@@ -3,8 +3,10 @@ package main
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/BurntSushi/toml"
+	"github.com/Koshroy/turnover/controllers"
 )
 
 func TestLoadConfig(t *testing.T) {
@@ -51,3 +53,272 @@ func TestLoadConfig(t *testing.T) {
 		)
 	}
 }
+
+func TestValidateConfigOptionalPublicKey(t *testing.T) {
+	configData := `
+        [server]
+        scheme = "https"
+        hostname = "example.com"
+        private_key = "example.pem"
+        `
+
+	var config Config
+	_, err := toml.DecodeReader(strings.NewReader(configData), &config)
+	if err != nil {
+		t.Errorf("could not parse example config properly")
+	}
+
+	err = ValidateConfig(config)
+	if err != nil {
+		t.Errorf("config without public key should validate, got: %v", err)
+	}
+}
+
+func TestValidateConfigKeyGracePeriod(t *testing.T) {
+	config := Config{
+		Server: ServerConfig{
+			Scheme:         "https",
+			Hostname:       "example.com",
+			PrivateKey:     "example.pem",
+			KeyGracePeriod: "not a duration",
+		},
+	}
+
+	err := ValidateConfig(config)
+	if err == nil {
+		t.Errorf("expected invalid key grace period to fail validation")
+	}
+
+	config.Server.KeyGracePeriod = "48h"
+	grace, err := config.Server.KeyGrace()
+	if err != nil || grace != 48*time.Hour {
+		t.Errorf("expected key grace period of 48h got %v (%v)", grace, err)
+	}
+}
+
+func TestValidateConfigDedupWindow(t *testing.T) {
+	config := Config{
+		Server: ServerConfig{
+			Scheme:     "https",
+			Hostname:   "example.com",
+			PrivateKey: "example.pem",
+		},
+	}
+
+	window, err := config.Relay.DedupDuration()
+	if err != nil || window != defaultDedupWindow {
+		t.Errorf("expected default dedup window got %v (%v)", window, err)
+	}
+
+	for _, invalid := range []string{"not a duration", "0s", "-1h"} {
+		config.Relay.DedupWindow = invalid
+		err = ValidateConfig(config)
+		if err == nil {
+			t.Errorf("expected dedup window %q to fail validation", invalid)
+		}
+	}
+
+	config.Relay.DedupWindow = "1h"
+	window, err = config.Relay.DedupDuration()
+	if err != nil || window != time.Hour {
+		t.Errorf("expected dedup window of 1h got %v (%v)", window, err)
+	}
+}
+
+func TestValidateConfigWorkers(t *testing.T) {
+	config := Config{
+		Server: ServerConfig{
+			Scheme:     "https",
+			Hostname:   "example.com",
+			PrivateKey: "example.pem",
+		},
+	}
+
+	if workers := config.Relay.WorkerCount(); workers != defaultWorkers {
+		t.Errorf("expected %d workers by default got %d", defaultWorkers, workers)
+	}
+
+	config.Relay.Workers = 8
+	if workers := config.Relay.WorkerCount(); workers != 8 {
+		t.Errorf("expected 8 workers got %d", workers)
+	}
+
+	config.Relay.Workers = -1
+	if err := ValidateConfig(config); err == nil {
+		t.Error("expected a negative worker count to fail validation")
+	}
+}
+
+func TestLimitsConfig(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		name    string
+		config  LimitsConfig
+		wantErr bool
+	}{
+		{"defaults", LimitsConfig{}, false},
+		{"all set", LimitsConfig{MaxBodySize: 4096, MaxDepth: 8, MaxNodes: 64, MaxExpansionTime: "1s"}, false},
+		{"negative body size", LimitsConfig{MaxBodySize: -1}, true},
+		{"negative depth", LimitsConfig{MaxDepth: -1}, true},
+		{"invalid expansion time", LimitsConfig{MaxExpansionTime: "soon"}, true},
+		{"zero expansion time", LimitsConfig{MaxExpansionTime: "0s"}, true},
+	}
+
+	for _, tt := range tests {
+		limits, err := tt.config.Limits()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: expected error %v got %v", tt.name, tt.wantErr, err)
+		}
+		if tt.wantErr {
+			continue
+		}
+
+		defaults := controllers.DefaultLimits()
+		if tt.config == (LimitsConfig{}) && limits != defaults {
+			t.Errorf("%s: expected default limits got %+v", tt.name, limits)
+		}
+		if tt.config.MaxNodes != 0 && limits.Nodes != tt.config.MaxNodes {
+			t.Errorf("%s: expected %d nodes got %d", tt.name, tt.config.MaxNodes, limits.Nodes)
+		}
+	}
+}
+
+func TestFetchConfig(t *testing.T) {
+	t.Parallel()
+
+	opts, err := FetchConfig{}.Options()
+	if err != nil || opts.Timeout != defaultFetchTimeout || opts.CacheSize != defaultFetchCacheSize {
+		t.Errorf("expected default fetch options got %+v (%v)", opts, err)
+	}
+
+	opts, err = FetchConfig{Timeout: "3s", CacheTTL: "1m", CacheSize: 10, MaxSize: 4096}.Options()
+	if err != nil || opts.Timeout != 3*time.Second || opts.CacheTTL != time.Minute || opts.CacheSize != 10 || opts.MaxSize != 4096 {
+		t.Errorf("unexpected fetch options %+v (%v)", opts, err)
+	}
+
+	for _, invalid := range []FetchConfig{
+		{Timeout: "soon"},
+		{Timeout: "0s"},
+		{CacheTTL: "-1m"},
+		{CacheSize: -1},
+		{MaxSize: -1},
+	} {
+		_, err = invalid.Options()
+		if err == nil {
+			t.Errorf("expected %+v to be invalid", invalid)
+		}
+	}
+}
+
+func TestValidateConfigPrivateKeySources(t *testing.T) {
+	config := Config{
+		Server: ServerConfig{
+			Scheme:        "https",
+			Hostname:      "example.com",
+			PrivateKeyEnv: "TURNOVER_PRIVATE_KEY",
+		},
+	}
+
+	err := ValidateConfig(config)
+	if err != nil {
+		t.Errorf("config with private key from environment should validate, got: %v", err)
+	}
+
+	config.Server.PrivateKey = "example.pem"
+	err = ValidateConfig(config)
+	if err == nil {
+		t.Errorf("expected config with two private key sources to fail validation")
+	}
+
+	config.Server.PrivateKey = ""
+	config.Server.PrivateKeyEnv = ""
+	err = ValidateConfig(config)
+	if err == nil {
+		t.Errorf("expected config without private key to fail validation")
+	}
+}
+
+func TestValidateConfigActor(t *testing.T) {
+	configData := `
+        [server]
+        scheme = "https"
+        hostname = "example.com"
+        private_key = "example.pem"
+
+        [actor]
+        name = "Example Relay"
+        summary = "<p>Relays posts</p>"
+        preferred_username = "news"
+        icon = "https://example.com/icon.png"
+        manually_approves_followers = true
+        discoverable = true
+
+        [[actor.attachment]]
+        name = "Contact"
+        value = "admin@example.com"
+        `
+
+	var config Config
+	_, err := toml.DecodeReader(strings.NewReader(configData), &config)
+	if err != nil {
+		t.Fatalf("could not parse example config properly: %v", err)
+	}
+
+	err = ValidateConfig(config)
+	if err != nil {
+		t.Fatalf("could not validate config: %v", err)
+	}
+
+	profile := config.Actor.Profile()
+	if profile.Username() != "news" || profile.DisplayName() != "Example Relay" {
+		t.Errorf("unexpected profile %+v", profile)
+	}
+	if !profile.ManuallyApprovesFollowers || !profile.Discoverable {
+		t.Errorf("expected profile flags to be set got %+v", profile)
+	}
+	if len(profile.Attachments) != 1 || profile.Attachments[0].Name != "Contact" {
+		t.Errorf("expected one Contact attachment got %+v", profile.Attachments)
+	}
+
+	var invalid = []struct {
+		name  string
+		actor ActorConfig
+	}{
+		{"username with spaces", ActorConfig{PreferredUsername: "my relay"}},
+		{"username with at sign", ActorConfig{PreferredUsername: "relay@example.com"}},
+		{"relative icon", ActorConfig{Icon: "/icon.png"}},
+		{"non http image", ActorConfig{Image: "ftp://example.com/header.png"}},
+		{"unnamed attachment", ActorConfig{Attachments: []ActorAttachment{{Value: "x"}}}},
+	}
+
+	for _, tt := range invalid {
+		config.Actor = tt.actor
+		if ValidateConfig(config) == nil {
+			t.Errorf("%s: expected validation to fail", tt.name)
+		}
+	}
+}
+
+func TestValidateConfigPolicies(t *testing.T) {
+	config := Config{
+		Server: ServerConfig{
+			Scheme:     "https",
+			Hostname:   "example.com",
+			PrivateKey: "example.pem",
+		},
+		Relay: RelayConfig{
+			Policies: map[string]string{"Announce": "ignore", "Flag": "relay"},
+		},
+	}
+
+	err := ValidateConfig(config)
+	if err != nil {
+		t.Errorf("could not validate config: %v", err)
+	}
+
+	config.Relay.Policies = map[string]string{"Follow": "relay"}
+	if ValidateConfig(config) == nil {
+		t.Error("expected relaying follows to be rejected")
+	}
+}
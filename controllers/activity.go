@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Koshroy/turnover/activities"
+	"github.com/go-chi/chi"
+	"github.com/gofrs/uuid"
+)
+
+// Activities is the controller logic for the /activities/{id} endpoint,
+// which dereferences activities published by the relay
+type Activities struct {
+	actor Actor
+	store activities.Store
+}
+
+// NewActivities creates a new Activities controller
+func NewActivities(actor Actor, store activities.Store) Activities {
+	return Activities{
+		actor: actor,
+		store: store,
+	}
+}
+
+// ServeHTTP serves the activity with the {id} route parameter, or a
+// Tombstone with 410 Gone if the activity has been deleted. Activities the
+// store has dropped to stay within its size are unknown and answer 404
+func (a Activities) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.FromString(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "unknown activity", http.StatusNotFound)
+		return
+	}
+
+	activity, ok := a.store.Get(id)
+	if !ok {
+		http.Error(w, "unknown activity", http.StatusNotFound)
+		return
+	}
+
+	doc, err := activityDocument(activity, a.actor.ActivityID(id))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	status := http.StatusOK
+	if !activity.Deleted.IsZero() {
+		status = http.StatusGone
+	}
+	writeJSONStatus(w, status, activityJSONType, doc)
+}
+
+// activityDocument returns the JSON-LD document of a stored activity with
+// the ActivityStreams context, or a Tombstone if it has been deleted
+func activityDocument(activity activities.Activity, iri string) (map[string]interface{}, error) {
+	if !activity.Deleted.IsZero() {
+		return map[string]interface{}{
+			"@context":   activityStreamsContext,
+			"id":         iri,
+			"type":       "Tombstone",
+			"formerType": activity.Type,
+			"deleted":    activity.Deleted.UTC().Format(time.RFC3339),
+		}, nil
+	}
+
+	var doc map[string]interface{}
+	err := json.Unmarshal(activity.Data, &doc)
+	if err != nil {
+		return nil, fmt.Errorf("could not unmarshal stored activity %s: %v", activity.ID, err)
+	}
+
+	if _, ok := doc["@context"]; !ok {
+		doc["@context"] = activityStreamsContext
+	}
+	return doc, nil
+}
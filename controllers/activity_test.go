@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Koshroy/turnover/activities"
+	"github.com/Koshroy/turnover/keystore"
+	"github.com/go-chi/chi"
+	"github.com/gofrs/uuid"
+)
+
+func getActivity(t *testing.T, a Activities, id string) (int, map[string]interface{}) {
+	req := httptest.NewRequest("GET", "/activities/"+id, nil)
+	routeCtx := chi.NewRouteContext()
+	routeCtx.URLParams.Add("id", id)
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, routeCtx))
+
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, req)
+
+	var doc map[string]interface{}
+	if w.Header().Get("Content-Type") == activityJSONType {
+		err := json.Unmarshal(w.Body.Bytes(), &doc)
+		if err != nil {
+			t.Fatalf("could not unmarshal activity: %v", err)
+		}
+	}
+	return w.Code, doc
+}
+
+func TestActivitiesHandler(t *testing.T) {
+	t.Parallel()
+
+	store := activities.NewMemoryStore(0)
+	actor := NewActor("https", "www.example.com", keystore.MockStore(), Profile{})
+	publisher := NewPublisher(actor, store, NewDeliverer(newMockQueuer(), newMockStorer(), http.DefaultClient, nil, nil))
+	a := NewActivities(actor, store)
+
+	published, err := publisher.Publish("Announce", "https://sally.example.org/notes/1", []string{publicIRI}, nil, nil)
+	if err != nil {
+		t.Fatalf("could not publish announce: %v", err)
+	}
+
+	activityIRI := published["id"].(string)
+	id := activityIRI[strings.LastIndex(activityIRI, "/")+1:]
+
+	code, doc := getActivity(t, a, id)
+	if code != http.StatusOK {
+		t.Fatalf("expected 200 got %d", code)
+	}
+	testStrings(t, doc, []stringTest{
+		{"@context", activityStreamsContext},
+		{"id", activityIRI},
+		{"type", "Announce"},
+		{"object", "https://sally.example.org/notes/1"},
+	})
+
+	activityID, err := uuid.FromString(id)
+	if err != nil {
+		t.Fatalf("could not parse activity id: %v", err)
+	}
+
+	err = publisher.Retract(activityID, []string{publicIRI}, nil)
+	if err != nil {
+		t.Fatalf("could not retract announce: %v", err)
+	}
+
+	code, doc = getActivity(t, a, id)
+	if code != http.StatusGone {
+		t.Fatalf("expected 410 for deleted activity got %d", code)
+	}
+	testStrings(t, doc, []stringTest{
+		{"id", activityIRI},
+		{"type", "Tombstone"},
+		{"formerType", "Announce"},
+	})
+
+	for _, unknown := range []string{"not-a-uuid", uuid.Must(uuid.NewV4()).String()} {
+		code, _ = getActivity(t, a, unknown)
+		if code != http.StatusNotFound {
+			t.Errorf("expected 404 for %s got %d", unknown, code)
+		}
+	}
+}
@@ -1,63 +1,200 @@
 package controllers
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"log"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/Koshroy/turnover/keystore"
+	"github.com/gofrs/uuid"
 )
 
+const dataIntegrityContext = "https://w3id.org/security/data-integrity/v1"
+
+// actorCacheControl lets remote servers cache the actor document for a
+// short while, since it only changes on key rotation
+const actorCacheControl = "public, max-age=300"
+
 // Actor is the controller logic for the /actor endpoint
 type Actor struct {
 	Scheme, Domain string
 	Store          *keystore.Store
-	PubKeyPem      string
+	Profile        Profile
+
+	cache *actorCache
+}
+
+// actorCache holds the serialized actor document and its ETag
+type actorCache struct {
+	mu   sync.RWMutex
+	body []byte
+	etag string
+}
+
+// NewActor creates a new Actor. The actor document is serialized once and
+// serialized again whenever the keys in store change
+func NewActor(scheme, domain string, store *keystore.Store, profile Profile) Actor {
+	a := Actor{
+		Scheme:  scheme,
+		Domain:  domain,
+		Store:   store,
+		Profile: profile,
+		cache:   &actorCache{},
+	}
+	a.refresh()
+	store.OnChange(a.refresh)
+	return a
 }
 
-// NewActor creates a new Actor
-func NewActor(scheme, domain string, store *keystore.Store) Actor {
-	return Actor{
-		Scheme:    scheme,
-		Domain:    domain,
-		Store:     store,
-		PubKeyPem: string(store.PubKeyPem()),
+// refresh serializes the actor document into the cache
+func (a Actor) refresh() {
+	b, err := json.Marshal(a.Document())
+	if err != nil {
+		log.Printf("error serializing actor document: %v\n", err)
+		return
 	}
+
+	sum := sha256.Sum256(b)
+	a.cache.mu.Lock()
+	a.cache.body = b
+	a.cache.etag = `"` + hex.EncodeToString(sum[:16]) + `"`
+	a.cache.mu.Unlock()
 }
 
 func (a Actor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	actorData := map[string]interface{}{
-		"@context": []string{
-			"https://www.w3.org/ns/activitystreams",
-			"https://web-payments.org/contexts/security-v1.jsonld",
-		},
+	a.cache.mu.RLock()
+	body, etag := a.cache.body, a.cache.etag
+	a.cache.mu.RUnlock()
+
+	if body == nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", actorCacheControl)
+
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", activityJSONType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	_, err := w.Write(body)
+	if err != nil {
+		log.Printf("error writing response: %v\n", err)
+	}
+}
+
+// etagMatches reports whether an If-None-Match header matches etag, using
+// the weak comparison RFC 9110 requires for If-None-Match
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// ID returns the IRI of the relay actor
+func (a Actor) ID() string {
+	return a.routeURL("/actor", "").String()
+}
+
+// PreferredUsername returns the username of the relay actor
+func (a Actor) PreferredUsername() string {
+	return a.Profile.Username()
+}
+
+// ActivityID returns the IRI of an activity published by the relay actor
+func (a Actor) ActivityID(id uuid.UUID) string {
+	return activityIRI(a.Scheme, a.Domain, id)
+}
+
+// KeyID returns the IRI of the key with the given ID on the relay actor
+func (a Actor) KeyID(id string) string {
+	return a.routeURL("/actor", id).String()
+}
+
+// Document returns the actor document. While old keys are still being
+// advertised after a rotation, publicKey is a list with the active key first.
+// An Ed25519 key is published as a FEP-521a Multikey under assertionMethod
+func (a Actor) Document() map[string]interface{} {
+	keys := a.Store.Keys()
+	pubKeys := make([]map[string]string, 0, len(keys))
+	for _, key := range keys {
+		pubKeys = append(pubKeys, map[string]string{
+			"publicKeyPem": string(key.PubKeyPem),
+			"owner":        a.ID(),
+			"id":           a.KeyID(key.ID),
+		})
+	}
+
+	var publicKey interface{} = pubKeys
+	if len(pubKeys) == 1 {
+		publicKey = pubKeys[0]
+	}
+
+	context := []interface{}{
+		activityStreamsContext,
+		"https://web-payments.org/contexts/security-v1.jsonld",
+	}
+
+	doc := map[string]interface{}{
 		"type":      "Application",
-		"following": a.routeURL("/following", "").String(),
 		"followers": a.routeURL("/followers", "").String(),
 		"inbox":     a.routeURL("/inbox", "").String(),
 		"outbox":    a.routeURL("/outbox", "").String(),
-		"id":        a.routeURL("/actor", "").String(),
-		"name":      "turnover relay",
-		"summary":   "An ActivityPub Relay",
-		"url":       a.routeURL("/actor", "").String(),
-		"publicKey": map[string]string{
-			"publicKeyPem": a.PubKeyPem,
-			"owner":        a.routeURL("/actor", "").String(),
-			"id":           a.routeURL("/actor", "#main-key").String(),
-		},
+		"id":        a.ID(),
+		"url":       a.ID(),
+		"publicKey": publicKey,
 	}
+	a.Profile.addTo(doc)
 
-	b, err := json.Marshal(actorData)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		return
+	edKey, ok := a.Store.Ed25519Key()
+	if ok {
+		context = append(context, dataIntegrityContext)
+		doc["assertionMethod"] = []map[string]string{
+			{
+				"id":                 a.KeyID(edKey.ID),
+				"type":               "Multikey",
+				"controller":         a.ID(),
+				"publicKeyMultibase": keystore.EncodeMultikey(edKey.PubKey),
+			},
+		}
 	}
 
-	_, err = w.Write(b)
-	if err != nil {
-		log.Printf("error writing response: %v\n", err)
+	doc["@context"] = append(context, profileContext)
+	return doc
+}
+
+func activityIRI(scheme, domain string, id uuid.UUID) string {
+	u := url.URL{
+		Scheme: scheme,
+		Host:   domain,
+		Path:   "/activities/" + id.String(),
 	}
+	return u.String()
 }
 
 func (a Actor) routeURL(path, fragment string) *url.URL {
@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"bytes"
+	"html/template"
+	"log"
+	"net/http"
+
+	"github.com/Koshroy/turnover/subscribers"
+)
+
+var actorPageTemplate = template.Must(template.New("actor").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Name}}</title>
+<link rel="alternate" type="application/activity+json" href="{{.ActorID}}">
+</head>
+<body>
+<h1>{{.Name}}</h1>
+<div>{{.Summary}}</div>
+<h2>Policy</h2>
+{{if .Whitelist}}<p>Only these instances may subscribe:</p>
+<ul>{{range .Whitelist}}
+<li>{{.}}</li>{{end}}
+</ul>{{else}}<p>Any instance may subscribe.</p>{{end}}
+<p>{{.Subscribers}} {{if eq .Subscribers 1}}instance is{{else}}instances are{{end}} subscribed.</p>
+<h2>How to subscribe</h2>
+<p>Mastodon and compatible software: add <code>{{.InboxID}}</code> as a relay.</p>
+<p>Pleroma, Akkoma and other LitePub software: follow <code>{{.ActorID}}</code>.</p>
+</body>
+</html>
+`))
+
+// ActorPage is the controller logic for the HTML profile page of the relay
+// actor, shown to browsers visiting /actor
+type ActorPage struct {
+	actor     Actor
+	whitelist []string
+	registry  subscribers.Registry
+}
+
+// NewActorPage creates a new ActorPage
+func NewActorPage(actor Actor, whitelist []string, registry subscribers.Registry) ActorPage {
+	return ActorPage{
+		actor:     actor,
+		whitelist: whitelist,
+		registry:  registry,
+	}
+}
+
+func (p ActorPage) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	profile := p.actor.Profile
+	data := struct {
+		Name             string
+		Summary          template.HTML
+		ActorID, InboxID string
+		Whitelist        []string
+		Subscribers      int
+	}{
+		Name: profile.DisplayName(),
+		// the summary is HTML written by the operator
+		Summary:     template.HTML(profile.SummaryHTML()),
+		ActorID:     p.actor.ID(),
+		InboxID:     p.actor.routeURL("/inbox", "").String(),
+		Whitelist:   p.whitelist,
+		Subscribers: p.registry.Count(),
+	}
+
+	var b bytes.Buffer
+	err := actorPageTemplate.Execute(&b, data)
+	if err != nil {
+		log.Printf("error rendering actor page: %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, err = w.Write(b.Bytes())
+	if err != nil {
+		log.Printf("error writing response: %v\n", err)
+	}
+}
@@ -0,0 +1,44 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Koshroy/turnover/keystore"
+	"github.com/Koshroy/turnover/subscribers"
+)
+
+func TestActorPageHandler(t *testing.T) {
+	t.Parallel()
+
+	registry := subscribers.NewMemoryRegistry()
+	registry.Add(subscribers.Subscriber{Actor: "https://sally.example.org/actor"})
+	actor := NewActor("https", "www.example.com", keystore.MockStore(), Profile{})
+	page := NewActorPage(actor, []string{"sally.example.org"}, registry)
+
+	w := httptest.NewRecorder()
+	page.ServeHTTP(w, httptest.NewRequest("GET", "/actor", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 got %d", w.Code)
+	}
+
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") {
+		t.Errorf("expected text/html got %s", w.Header().Get("Content-Type"))
+	}
+
+	body := w.Body.String()
+	for _, expected := range []string{
+		"turnover relay",
+		"sally.example.org",
+		"1 instance is subscribed",
+		"https://www.example.com/inbox",
+		"https://www.example.com/actor",
+	} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("expected actor page to contain %q", expected)
+		}
+	}
+}
@@ -1,10 +1,14 @@
 package controllers
 
 import (
+	"crypto/ed25519"
+	"crypto/rand"
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/Koshroy/turnover/keystore"
@@ -57,7 +61,7 @@ func checkPubKeyPem(t *testing.T, resp map[string]interface{}, pubKeyPemStr stri
 func TestActorHandler(t *testing.T) {
 	t.Parallel()
 
-	a := NewActor("https", "www.example.com", keystore.MockStore())
+	a := NewActor("https", "www.example.com", keystore.MockStore(), Profile{})
 
 	req := httptest.NewRequest("", "/", nil)
 	w := httptest.NewRecorder()
@@ -89,12 +93,190 @@ func TestActorHandler(t *testing.T) {
 			{"id", "https://www.example.com/actor"},
 			{"type", "Application"},
 			{"followers", "https://www.example.com/followers"},
-			{"following", "https://www.example.com/following"},
 			{"url", "https://www.example.com/actor"},
 			{"inbox", "https://www.example.com/inbox"},
 			{"outbox", "https://www.example.com/outbox"},
+			{"preferredUsername", "relay"},
 		},
 	)
 
 	checkPubKeyPem(t, respData, string(keystore.MockPubKey))
 }
+
+func TestActorEd25519Multikey(t *testing.T) {
+	t.Parallel()
+
+	_, privKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate Ed25519 key: %v", err)
+	}
+
+	store := keystore.MockStore()
+	store.SetEd25519Key(privKey)
+	a := NewActor("https", "www.example.com", store, Profile{})
+
+	methods, ok := a.Document()["assertionMethod"].([]map[string]string)
+	if !ok || len(methods) != 1 {
+		t.Fatalf("expected one assertionMethod got %v", a.Document()["assertionMethod"])
+	}
+
+	testStrings(t, map[string]interface{}{
+		"id":                 methods[0]["id"],
+		"type":               methods[0]["type"],
+		"controller":         methods[0]["controller"],
+		"publicKeyMultibase": methods[0]["publicKeyMultibase"],
+	}, []stringTest{
+		{"id", "https://www.example.com/actor#ed25519-key"},
+		{"type", "Multikey"},
+		{"controller", "https://www.example.com/actor"},
+		{"publicKeyMultibase", keystore.EncodeMultikey(privKey.Public().(ed25519.PublicKey))},
+	})
+}
+
+func TestActorCaching(t *testing.T) {
+	t.Parallel()
+
+	store := keystore.MockStore()
+	a := NewActor("https", "www.example.com", store, Profile{})
+
+	get := func(method, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/actor", nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		a.ServeHTTP(w, req)
+		return w
+	}
+
+	first := get("GET", "")
+	etag := first.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("expected an ETag header")
+	}
+	if first.Header().Get("Content-Type") != activityJSONType {
+		t.Errorf("expected content type %s got %q", activityJSONType, first.Header().Get("Content-Type"))
+	}
+	if first.Header().Get("Cache-Control") == "" {
+		t.Error("expected a Cache-Control header")
+	}
+
+	var tests = []struct {
+		name        string
+		method      string
+		ifNoneMatch string
+		status      int
+		emptyBody   bool
+	}{
+		{"matching etag", "GET", etag, http.StatusNotModified, true},
+		{"weak matching etag", "GET", "W/" + etag, http.StatusNotModified, true},
+		{"etag in list", "GET", `"other", ` + etag, http.StatusNotModified, true},
+		{"wildcard", "GET", "*", http.StatusNotModified, true},
+		{"stale etag", "GET", `"stale"`, http.StatusOK, false},
+		{"head request", "HEAD", "", http.StatusOK, true},
+	}
+
+	for _, tt := range tests {
+		w := get(tt.method, tt.ifNoneMatch)
+		if w.Code != tt.status {
+			t.Errorf("%s: expected status %d got %d", tt.name, tt.status, w.Code)
+		}
+		if (w.Body.Len() == 0) != tt.emptyBody {
+			t.Errorf("%s: expected empty body %v got %d bytes", tt.name, tt.emptyBody, w.Body.Len())
+		}
+		if w.Header().Get("ETag") != etag {
+			t.Errorf("%s: expected ETag %s got %s", tt.name, etag, w.Header().Get("ETag"))
+		}
+	}
+
+	if head := get("HEAD", ""); head.Header().Get("Content-Length") != strconv.Itoa(first.Body.Len()) {
+		t.Errorf("expected HEAD Content-Length %d got %s", first.Body.Len(), head.Header().Get("Content-Length"))
+	}
+
+	_, privKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate Ed25519 key: %v", err)
+	}
+	store.SetEd25519Key(privKey)
+
+	updated := get("GET", etag)
+	if updated.Code != http.StatusOK {
+		t.Fatalf("expected 200 after key change got %d", updated.Code)
+	}
+	if updated.Header().Get("ETag") == etag {
+		t.Error("expected ETag to change after key change")
+	}
+	if !strings.Contains(updated.Body.String(), "assertionMethod") {
+		t.Error("expected refreshed document to include the Ed25519 key")
+	}
+}
+
+func TestActorProfile(t *testing.T) {
+	t.Parallel()
+
+	profile := Profile{
+		Name:                      "Example Relay",
+		Summary:                   "<p>Relays posts</p>",
+		PreferredUsername:         "news",
+		Icon:                      "https://www.example.com/icon.png",
+		Image:                     "https://www.example.com/header",
+		ManuallyApprovesFollowers: true,
+		Discoverable:              true,
+		Attachments: []ProfileField{
+			{Name: "Contact", Value: "admin@example.com"},
+		},
+	}
+	a := NewActor("https", "www.example.com", keystore.MockStore(), profile)
+
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, httptest.NewRequest("GET", "/actor", nil))
+
+	var doc map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &doc)
+	if err != nil {
+		t.Fatalf("could not unmarshal actor document: %v", err)
+	}
+
+	testStrings(t, doc, []stringTest{
+		{"name", "Example Relay"},
+		{"summary", "<p>Relays posts</p>"},
+		{"preferredUsername", "news"},
+	})
+
+	if doc["manuallyApprovesFollowers"] != true || doc["discoverable"] != true {
+		t.Errorf("expected profile flags got %v and %v", doc["manuallyApprovesFollowers"], doc["discoverable"])
+	}
+
+	icon, _ := doc["icon"].(map[string]interface{})
+	if icon["url"] != profile.Icon || icon["mediaType"] != "image/png" {
+		t.Errorf("unexpected icon %v", doc["icon"])
+	}
+
+	image, _ := doc["image"].(map[string]interface{})
+	if _, ok := image["mediaType"]; ok || image["url"] != profile.Image {
+		t.Errorf("unexpected image %v", doc["image"])
+	}
+
+	attachments, _ := doc["attachment"].([]interface{})
+	if len(attachments) != 1 {
+		t.Fatalf("expected one attachment got %v", doc["attachment"])
+	}
+	field := attachments[0].(map[string]interface{})
+	if field["type"] != "PropertyValue" || field["name"] != "Contact" || field["value"] != "admin@example.com" {
+		t.Errorf("unexpected attachment %v", field)
+	}
+
+	wf := NewWebFinger(a)
+	for _, resource := range []string{"acct:news@www.example.com", "acct:relay@www.example.com"} {
+		w := httptest.NewRecorder()
+		wf.ServeHTTP(w, httptest.NewRequest("GET", "/.well-known/webfinger?resource="+resource, nil))
+
+		want := http.StatusOK
+		if resource == "acct:relay@www.example.com" {
+			want = http.StatusNotFound
+		}
+		if w.Code != want {
+			t.Errorf("webfinger for %s expected %d got %d", resource, want, w.Code)
+		}
+	}
+}
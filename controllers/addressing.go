@@ -0,0 +1,54 @@
+package controllers
+
+import (
+	"log"
+
+	"github.com/Koshroy/turnover/models"
+)
+
+// publicIRIs are the ways the public collection appears in expanded
+// addressing. Compacted as:Public expands to the full IRI, while a bare
+// Public is left as a relative IRI by expansion
+var publicIRIs = map[string]bool{
+	publicIRI:   true,
+	"as:Public": true,
+	"Public":    true,
+}
+
+// isPublic reports whether activity is addressed to the public collection
+// through to, cc or audience. An embedded object carrying its own
+// addressing must be public as well, so followers-only posts wrapped in a
+// public activity are not relayed
+func isPublic(activity *models.Activity) bool {
+	if !addressedToPublic(activity) {
+		return false
+	}
+
+	for idx := range activity.Object {
+		object := &activity.Object[idx]
+		if hasAddressing(object) && !addressedToPublic(object) {
+			return false
+		}
+	}
+	return true
+}
+
+func addressedToPublic(activity *models.Activity) bool {
+	for _, audience := range []interface{}{activity.To, activity.CC, activity.Audience} {
+		for _, id := range nodeIDs(audience) {
+			if publicIRIs[id] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func hasAddressing(activity *models.Activity) bool {
+	return activity.To != nil || activity.CC != nil || activity.Audience != nil
+}
+
+// auditDrop records that an activity was not relayed and why
+func auditDrop(activity *models.Activity, reason string) {
+	log.Printf("audit: not relaying activity %s from %s: %s\n", *activity.ID, nodeID(activity.Actor), reason)
+}
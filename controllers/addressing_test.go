@@ -0,0 +1,52 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/Koshroy/turnover/ldcontext"
+)
+
+// addressedCreate returns a Create whose activity and note use the given
+// addressing properties
+func addressedCreate(activityAddressing, noteAddressing string) string {
+	return fmt.Sprintf(`{
+    "@context": "https://www.w3.org/ns/activitystreams",
+    "type": "Create",
+    "id": "https://sally.example.org/activities/1",
+    "actor": "https://sally.example.org/users/sally",
+    %s
+    "object": {
+        %s
+        "id": "https://sally.example.org/notes/1",
+        "type": "Note",
+        "attributedTo": "https://sally.example.org/users/sally"
+    }
+}
+`, activityAddressing, noteAddressing)
+}
+
+func TestInboxPublicAddressing(t *testing.T) {
+	t.Parallel()
+
+	const public = `"https://www.w3.org/ns/activitystreams#Public"`
+	const followers = `"https://sally.example.org/users/sally/followers"`
+
+	q := newMockQueuer()
+	s := newMockStorer()
+	i := NewInbox([]string{}, "https", "www.example.com", ldcontext.NewLoader(), q, s, subscribedRegistry())
+
+	testResp(t, i, q, s, []respTest{
+		{addressedCreate(`"to": `+public+`,`, ""), http.StatusAccepted, 1, "public_to"},
+		{addressedCreate(`"to": [`+followers+`], "cc": [`+public+`],`, ""), http.StatusAccepted, 1, "public_cc"},
+		{addressedCreate(`"audience": `+public+`,`, ""), http.StatusAccepted, 1, "public_audience"},
+		{addressedCreate(`"to": "as:Public",`, ""), http.StatusAccepted, 1, "compact_iri_public"},
+		{addressedCreate(`"to": "Public",`, ""), http.StatusAccepted, 1, "term_public"},
+		{addressedCreate(`"to": `+public+`,`, `"to": `+public+`,`), http.StatusAccepted, 1, "public_note"},
+		{addressedCreate(`"to": `+followers+`,`, ""), http.StatusAccepted, 0, "followers_only"},
+		{addressedCreate(`"to": "https://john.example.org/users/john",`, ""), http.StatusAccepted, 0, "direct_message"},
+		{addressedCreate("", ""), http.StatusAccepted, 0, "unaddressed"},
+		{addressedCreate(`"to": `+public+`,`, `"to": `+followers+`,`), http.StatusAccepted, 0, "followers_only_note"},
+	})
+}
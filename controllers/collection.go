@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/Koshroy/turnover/subscribers"
+)
+
+const defaultCollectionPageSize = 20
+
+// Collection is the controller logic for an OrderedCollection of actors
+// backed by a subscriber registry, such as /followers
+type Collection struct {
+	scheme, domain, path string
+	registry             subscribers.Registry
+	pageSize             int
+	hideMembers          bool
+}
+
+// NewCollection creates a new Collection served at path. When hideMembers is
+// set only totalItems is exposed and pages are not served
+func NewCollection(
+	scheme, domain, path string,
+	registry subscribers.Registry,
+	pageSize int,
+	hideMembers bool,
+) Collection {
+	if pageSize <= 0 {
+		pageSize = defaultCollectionPageSize
+	}
+
+	return Collection{
+		scheme:      scheme,
+		domain:      domain,
+		path:        path,
+		registry:    registry,
+		pageSize:    pageSize,
+		hideMembers: hideMembers,
+	}
+}
+
+func (c Collection) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	pageParam := r.URL.Query().Get("page")
+	if pageParam == "" {
+		writeJSON(w, activityJSONType, c.collection())
+		return
+	}
+
+	if c.hideMembers {
+		http.Error(w, "collection members are not public", http.StatusForbidden)
+		return
+	}
+
+	page, err := strconv.Atoi(pageParam)
+	if err != nil || page < 1 {
+		http.Error(w, "invalid page", http.StatusBadRequest)
+		return
+	}
+
+	if page > lastPage(c.registry.Count(), c.pageSize) {
+		http.Error(w, "page not found", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, activityJSONType, c.page(page))
+}
+
+// lastPage returns the number of the last page of a collection of total
+// items. An empty collection still has an empty first page
+func lastPage(total, pageSize int) int {
+	if total <= 0 {
+		return 1
+	}
+	return (total + pageSize - 1) / pageSize
+}
+
+func (c Collection) collection() map[string]interface{} {
+	doc := map[string]interface{}{
+		"@context":   activityStreamsContext,
+		"id":         c.pageURL(0),
+		"type":       "OrderedCollection",
+		"totalItems": c.registry.Count(),
+	}
+
+	if !c.hideMembers {
+		doc["first"] = c.pageURL(1)
+	}
+	return doc
+}
+
+func (c Collection) page(page int) map[string]interface{} {
+	subs := c.registry.List()
+	start := (page - 1) * c.pageSize
+	if start > len(subs) {
+		start = len(subs)
+	}
+	end := start + c.pageSize
+	if end > len(subs) {
+		end = len(subs)
+	}
+
+	items := make([]string, 0, end-start)
+	for _, sub := range subs[start:end] {
+		items = append(items, sub.Actor)
+	}
+
+	doc := map[string]interface{}{
+		"@context":     activityStreamsContext,
+		"id":           c.pageURL(page),
+		"type":         "OrderedCollectionPage",
+		"partOf":       c.pageURL(0),
+		"totalItems":   len(subs),
+		"orderedItems": items,
+	}
+
+	if end < len(subs) {
+		doc["next"] = c.pageURL(page + 1)
+	}
+	if page > 1 {
+		doc["prev"] = c.pageURL(page - 1)
+	}
+	return doc
+}
+
+// pageURL returns the URL of the given page, or of the collection itself
+// when page is 0
+func (c Collection) pageURL(page int) string {
+	u := url.URL{
+		Scheme: c.scheme,
+		Host:   c.domain,
+		Path:   c.path,
+	}
+
+	if page > 0 {
+		u.RawQuery = url.Values{"page": []string{strconv.Itoa(page)}}.Encode()
+	}
+	return u.String()
+}
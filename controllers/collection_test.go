@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Koshroy/turnover/subscribers"
+)
+
+func mockRegistry(n int) *subscribers.MemoryRegistry {
+	registry := subscribers.NewMemoryRegistry()
+	now := time.Now()
+	for i := 0; i < n; i++ {
+		registry.Add(subscribers.Subscriber{
+			Actor: fmt.Sprintf("https://%d.example.org/actor", i),
+			Since: now.Add(time.Duration(i) * time.Second),
+		})
+	}
+	return registry
+}
+
+func getCollection(t *testing.T, c Collection, target string) (int, map[string]interface{}) {
+	w := httptest.NewRecorder()
+	c.ServeHTTP(w, httptest.NewRequest("GET", target, nil))
+
+	if w.Code != http.StatusOK {
+		return w.Code, nil
+	}
+
+	var doc map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &doc)
+	if err != nil {
+		t.Fatalf("could not unmarshal collection: %v", err)
+	}
+	return w.Code, doc
+}
+
+func TestCollectionPaging(t *testing.T) {
+	t.Parallel()
+
+	c := NewCollection("https", "www.example.com", "/followers", mockRegistry(3), 2, false)
+
+	_, doc := getCollection(t, c, "/followers")
+	testStrings(t, doc, []stringTest{
+		{"id", "https://www.example.com/followers"},
+		{"type", "OrderedCollection"},
+		{"first", "https://www.example.com/followers?page=1"},
+	})
+	if doc["totalItems"] != float64(3) {
+		t.Errorf("expected 3 total items got %v", doc["totalItems"])
+	}
+
+	_, doc = getCollection(t, c, "/followers?page=1")
+	testStrings(t, doc, []stringTest{
+		{"type", "OrderedCollectionPage"},
+		{"partOf", "https://www.example.com/followers"},
+		{"next", "https://www.example.com/followers?page=2"},
+	})
+	items := doc["orderedItems"].([]interface{})
+	if len(items) != 2 || items[0] != "https://0.example.org/actor" {
+		t.Errorf("expected first two followers got %v", items)
+	}
+
+	_, doc = getCollection(t, c, "/followers?page=2")
+	items = doc["orderedItems"].([]interface{})
+	if len(items) != 1 || doc["next"] != nil || doc["prev"] != "https://www.example.com/followers?page=1" {
+		t.Errorf("expected last page with one follower got %v", doc)
+	}
+
+	code, _ := getCollection(t, c, "/followers?page=0")
+	if code != http.StatusBadRequest {
+		t.Errorf("expected invalid page to return 400 got %d", code)
+	}
+	for _, page := range []string{"3", "922337203685477581", "9223372036854775807"} {
+		code, _ = getCollection(t, c, "/followers?page="+page)
+		if code != http.StatusNotFound {
+			t.Errorf("expected page %s past the end to return 404 got %d", page, code)
+		}
+	}
+
+	empty := NewCollection("https", "www.example.com", "/followers", mockRegistry(0), 2, false)
+	code, _ = getCollection(t, empty, "/followers?page=1")
+	if code != http.StatusOK {
+		t.Errorf("expected the first page of an empty collection to return 200 got %d", code)
+	}
+}
+
+func TestCollectionHiddenMembers(t *testing.T) {
+	t.Parallel()
+
+	c := NewCollection("https", "www.example.com", "/followers", mockRegistry(3), 2, true)
+
+	_, doc := getCollection(t, c, "/followers")
+	if doc["totalItems"] != float64(3) || doc["first"] != nil {
+		t.Errorf("expected only totalItems for hidden collection got %v", doc)
+	}
+
+	code, _ := getCollection(t, c, "/followers?page=1")
+	if code != http.StatusForbidden {
+		t.Errorf("expected hidden collection page to return 403 got %d", code)
+	}
+}
@@ -0,0 +1,381 @@
+package controllers
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/Koshroy/turnover/ldcontext"
+	"github.com/Koshroy/turnover/models"
+)
+
+// activityStreamsTerms maps the terms of the ActivityStreams context to
+// their IRIs
+var activityStreamsTerms = ldcontext.Terms(activityStreamsContext)
+
+// activityStreamsVocab is the @vocab of the ActivityStreams context
+const activityStreamsVocab = "_:"
+
+// readProperties are the IRIs of the properties the fast path reads
+var readProperties = map[string]bool{
+	activityStreamsTerms["object"]:       true,
+	activityStreamsTerms["actor"]:        true,
+	activityStreamsTerms["target"]:       true,
+	activityStreamsTerms["result"]:       true,
+	activityStreamsTerms["origin"]:       true,
+	activityStreamsTerms["instrument"]:   true,
+	activityStreamsTerms["to"]:           true,
+	activityStreamsTerms["cc"]:           true,
+	activityStreamsTerms["audience"]:     true,
+	activityStreamsTerms["attributedTo"]: true,
+}
+
+// activityStreamsContexts are the URLs of the ActivityStreams context
+var activityStreamsContexts = map[string]bool{
+	activityStreamsContext:                         true,
+	"http://www.w3.org/ns/activitystreams":         true,
+	"https://www.w3.org/ns/activitystreams.jsonld": true,
+}
+
+// fastPathContexts maps the contexts an activity may use for the fast
+// path to the terms they define. None of them redefine ActivityStreams terms
+var fastPathContexts = map[string]map[string]string{
+	activityStreamsContext:                                 activityStreamsTerms,
+	"http://www.w3.org/ns/activitystreams":                 activityStreamsTerms,
+	"https://www.w3.org/ns/activitystreams.jsonld":         activityStreamsTerms,
+	"https://w3id.org/security/v1":                         ldcontext.Terms("https://w3id.org/security/v1"),
+	"https://web-payments.org/contexts/security-v1.jsonld": ldcontext.Terms("https://w3id.org/security/v1"),
+	"https://w3id.org/security/data-integrity/v1":          ldcontext.Terms("https://w3id.org/security/data-integrity/v1"),
+	"https://w3id.org/security/multikey/v1":                ldcontext.Terms("https://w3id.org/security/multikey/v1"),
+}
+
+// compactParser reads activities in their compacted form. otherTerms are
+// the terms defined by contexts other than ActivityStreams, which do not
+// fall back to the ActivityStreams vocabulary
+type compactParser struct {
+	otherTerms map[string]bool
+}
+
+// compactActivity builds an activity straight from its compacted form,
+// without running JSON-LD expansion. The result matches what expansion
+// followed by hydrateActivity would produce. It returns false when the
+// document uses anything it does not understand, such as an unusual
+// context, compact IRIs or embedded actors, and the caller must fall back
+// to expansion
+func compactActivity(raw map[string]interface{}) (*models.Activity, bool) {
+	p, ok := newCompactParser(raw["@context"])
+	if !ok {
+		return nil, false
+	}
+
+	return p.node(raw, true)
+}
+
+// newCompactParser returns a parser for documents using context, which
+// must be the ActivityStreams context, possibly alongside contexts
+// compatible with it. Inline context objects may add terms, but not
+// redefine ActivityStreams terms, alias keywords or the properties the
+// fast path reads, or change how IRIs expand
+func newCompactParser(context interface{}) (*compactParser, bool) {
+	p := &compactParser{otherTerms: make(map[string]bool)}
+
+	switch c := context.(type) {
+	case string:
+		return p, activityStreamsContexts[c]
+	case []interface{}:
+		hasActivityStreams := false
+		inline := make(map[string]string)
+		for _, entry := range c {
+			switch e := entry.(type) {
+			case string:
+				terms, ok := fastPathContexts[e]
+				if !ok {
+					return nil, false
+				}
+				if activityStreamsContexts[e] {
+					hasActivityStreams = true
+					continue
+				}
+				for term := range terms {
+					p.otherTerms[term] = true
+				}
+			case map[string]interface{}:
+				for term, definition := range e {
+					if strings.HasPrefix(term, "@") {
+						return nil, false
+					}
+					if _, ok := activityStreamsTerms[term]; ok || isKeywordAlias(term) {
+						return nil, false
+					}
+					iri, ok := definitionIRI(definition)
+					if !ok {
+						return nil, false
+					}
+					p.otherTerms[term] = true
+					inline[term] = iri
+				}
+			default:
+				return nil, false
+			}
+		}
+		for _, iri := range inline {
+			if aliasesReadProperty(iri, inline) {
+				return nil, false
+			}
+		}
+		return p, hasActivityStreams
+	default:
+		return nil, false
+	}
+}
+
+// definitionIRI returns the IRI an inline term definition maps its term
+// to. Definitions it cannot read, such as reverse properties, are not ok
+func definitionIRI(definition interface{}) (string, bool) {
+	switch def := definition.(type) {
+	case nil:
+		return "", true
+	case string:
+		return def, true
+	case map[string]interface{}:
+		if _, ok := def["@reverse"]; ok {
+			return "", false
+		}
+		if def["@id"] == nil {
+			return "", true
+		}
+		iri, ok := def["@id"].(string)
+		return iri, ok
+	default:
+		return "", false
+	}
+}
+
+// aliasesReadProperty reports whether an inline term mapped to iri would
+// expand to a keyword or to a property the fast path reads, either
+// directly, as a compact IRI or through another term. The fast path would
+// ignore such a term where expansion reads it
+func aliasesReadProperty(iri string, inline map[string]string) bool {
+	// bound the lookups, terms may refer to each other in a cycle
+	for n := 0; n < len(inline)+2 && iri != ""; n++ {
+		if strings.HasPrefix(iri, "@") || readProperties[iri] {
+			return true
+		}
+
+		prefix, suffix, found := strings.Cut(iri, ":")
+		if found && strings.HasPrefix(suffix, "//") {
+			return false
+		}
+
+		expanded, ok := inline[prefix]
+		if !ok {
+			expanded, ok = activityStreamsTerms[prefix]
+		}
+		if !ok {
+			return false
+		}
+		if found {
+			expanded += suffix
+		}
+		iri = expanded
+	}
+	return iri != ""
+}
+
+// node builds an activity from a compacted node object. Only the
+// top level node may carry a @context
+func (p *compactParser) node(node map[string]interface{}, top bool) (*models.Activity, bool) {
+	// expansion drops empty nodes, and top level nodes without properties
+	if len(node) == 0 {
+		return nil, false
+	}
+
+	var activity models.Activity
+	for key, value := range node {
+		switch key {
+		case "@context":
+			if !top {
+				return nil, false
+			}
+		case "id", "@id":
+			id, ok := value.(string)
+			if !ok || activity.ID != nil || !fastPathIRI(id) {
+				return nil, false
+			}
+			activity.ID = &id
+		case "type", "@type":
+			if activity.Type != nil {
+				return nil, false
+			}
+			types, ok := p.types(value)
+			if !ok {
+				return nil, false
+			}
+			activity.Type = types
+		case "object":
+			objects, ok := p.objects(value)
+			if !ok {
+				return nil, false
+			}
+			activity.Object = objects
+		case "actor", "target", "result", "origin", "instrument", "to", "cc", "audience", "attributedTo":
+			refs, ok := compactRefs(value)
+			if !ok {
+				return nil, false
+			}
+			if refs == nil {
+				continue
+			}
+			switch key {
+			case "actor":
+				activity.Actor = refs
+			case "target":
+				activity.Target = refs
+			case "result":
+				activity.Result = refs
+			case "origin":
+				activity.Origin = refs
+			case "instrument":
+				activity.Instrument = refs
+			case "to":
+				activity.To = refs
+			case "cc":
+				activity.CC = refs
+			case "audience":
+				activity.Audience = refs
+			case "attributedTo":
+				activity.AttributedTo = refs
+			}
+		default:
+			// Other plain terms expand to IRIs which activities do not
+			// keep, but keywords and compact or absolute IRIs might
+			// expand to one of the fields above
+			if strings.HasPrefix(key, "@") || strings.Contains(key, ":") {
+				return nil, false
+			}
+		}
+	}
+
+	if top && activity.Type == nil {
+		return nil, false
+	}
+
+	return &activity, true
+}
+
+// types expands a type or list of types. Terms the ActivityStreams context
+// does not define expand to blank node IRIs through its @vocab
+func (p *compactParser) types(value interface{}) ([]string, bool) {
+	values, ok := stringList(value)
+	if !ok || len(values) == 0 {
+		return nil, false
+	}
+
+	types := make([]string, 0, len(values))
+	for _, v := range values {
+		iri, ok := activityStreamsTerms[v]
+		if !ok {
+			if p.otherTerms[v] || v == "" || strings.HasPrefix(v, "@") || strings.Contains(v, ":") {
+				return nil, false
+			}
+			iri = activityStreamsVocab + v
+		}
+		types = append(types, iri)
+	}
+	return types, true
+}
+
+// objects builds the objects of an activity, which are IRIs or embedded
+// nodes
+func (p *compactParser) objects(value interface{}) ([]models.Activity, bool) {
+	if value == nil {
+		return nil, true
+	}
+
+	values, ok := value.([]interface{})
+	if !ok {
+		values = []interface{}{value}
+	}
+	if len(values) == 0 {
+		return nil, false
+	}
+
+	objects := make([]models.Activity, 0, len(values))
+	for _, v := range values {
+		switch o := v.(type) {
+		case string:
+			if !fastPathIRI(o) {
+				return nil, false
+			}
+			id := o
+			objects = append(objects, models.Activity{ID: &id})
+		case map[string]interface{}:
+			object, ok := p.node(o, false)
+			if !ok {
+				return nil, false
+			}
+			objects = append(objects, *object)
+		default:
+			return nil, false
+		}
+	}
+	return objects, true
+}
+
+// compactRefs expands a property holding IRIs into node references. A nil
+// result without failure means the property is null
+func compactRefs(value interface{}) (interface{}, bool) {
+	if value == nil {
+		return nil, true
+	}
+
+	iris, ok := stringList(value)
+	if !ok || len(iris) == 0 {
+		return nil, false
+	}
+
+	refs := make([]interface{}, 0, len(iris))
+	for _, iri := range iris {
+		if !fastPathIRI(iri) {
+			return nil, false
+		}
+		refs = append(refs, map[string]interface{}{"@id": iri})
+	}
+	return refs, true
+}
+
+// stringList returns a string or a list of strings as a list
+func stringList(value interface{}) ([]string, bool) {
+	switch v := value.(type) {
+	case string:
+		return []string{v}, true
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, entry := range v {
+			s, ok := entry.(string)
+			if !ok {
+				return nil, false
+			}
+			values = append(values, s)
+		}
+		return values, true
+	default:
+		return nil, false
+	}
+}
+
+// fastPathIRI reports whether iri expands to itself, which holds for
+// absolute http(s) IRIs and the empty IRI
+func fastPathIRI(iri string) bool {
+	if iri == "" {
+		return true
+	}
+
+	u, err := url.Parse(iri)
+	return err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != ""
+}
+
+// isKeywordAlias reports whether term is one of the aliases of JSON-LD
+// keywords the ActivityStreams context defines
+func isKeywordAlias(term string) bool {
+	return term == "id" || term == "type"
+}
@@ -0,0 +1,256 @@
+package controllers
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/Koshroy/turnover/ldcontext"
+)
+
+const mastodonCreateJSON = `{
+    "@context": [
+        "https://www.w3.org/ns/activitystreams",
+        "https://w3id.org/security/v1",
+        {
+            "toot": "http://joinmastodon.org/ns#",
+            "sensitive": "as:sensitive",
+            "Hashtag": "as:Hashtag"
+        }
+    ],
+    "id": "https://sally.example.org/users/sally/statuses/1/activity",
+    "type": "Create",
+    "actor": "https://sally.example.org/users/sally",
+    "published": "2024-01-01T00:00:00Z",
+    "to": ["https://www.w3.org/ns/activitystreams#Public"],
+    "cc": ["https://sally.example.org/users/sally/followers"],
+    "object": {
+        "id": "https://sally.example.org/users/sally/statuses/1",
+        "type": "Note",
+        "attributedTo": "https://sally.example.org/users/sally",
+        "content": "<p>hello</p>",
+        "sensitive": false,
+        "tag": [],
+        "to": ["https://www.w3.org/ns/activitystreams#Public"]
+    }
+}
+`
+
+const announceJSON = `{
+    "@context": "https://www.w3.org/ns/activitystreams",
+    "id": "https://sally.example.org/activities/announce/1",
+    "type": "Announce",
+    "actor": "https://sally.example.org/users/sally",
+    "object": "https://john.example.org/notes/1",
+    "to": "https://www.w3.org/ns/activitystreams#Public"
+}
+`
+
+// expandActivities parses doc through JSON-LD expansion only
+func expandActivities(t testing.TB, doc string) []interface{} {
+	i := NewInbox(nil, "https", "www.example.com", ldcontext.NewLoader(), nil, nil, subscribedRegistry())
+
+	var raw map[string]interface{}
+	err := json.Unmarshal([]byte(doc), &raw)
+	if err != nil {
+		t.Fatalf("could not unmarshal document: %v", err)
+	}
+
+	expanded, err := i.proc.Expand(raw, i.opts)
+	if err != nil {
+		t.Fatalf("could not expand document: %v", err)
+	}
+	return expanded
+}
+
+func TestCompactActivityMatchesExpansion(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		name string
+		doc  string
+	}{
+		{"follow", followJSON},
+		{"follow with empty id", emptyIDFollowJSON},
+		{"follow without id", missingIDFollowJSON},
+		{"create note", createNoteJSON},
+		{"mastodon create", mastodonCreateJSON},
+		{"announce", announceJSON},
+	}
+
+	for _, tt := range tests {
+		var tt = tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var raw map[string]interface{}
+			err := json.Unmarshal([]byte(tt.doc), &raw)
+			if err != nil {
+				t.Fatalf("could not unmarshal document: %v", err)
+			}
+
+			fast, ok := compactActivity(raw)
+			if !ok {
+				t.Fatal("expected the fast path to handle the document")
+			}
+
+			expanded := expandActivities(t, tt.doc)
+			if len(expanded) != 1 {
+				t.Fatalf("expected one expanded activity got %d", len(expanded))
+			}
+
+			fastBytes, err := json.Marshal(fast)
+			if err != nil {
+				t.Fatalf("could not marshal fast path activity: %v", err)
+			}
+			slowBytes, err := json.Marshal(expanded[0])
+			if err != nil {
+				t.Fatalf("could not marshal expanded activity: %v", err)
+			}
+
+			var fastDoc, slowDoc map[string]interface{}
+			_ = json.Unmarshal(fastBytes, &fastDoc)
+			_ = json.Unmarshal(slowBytes, &slowDoc)
+			// expansion keeps every property, so only compare the
+			// properties activities hold
+			for key := range slowDoc {
+				if _, ok := fastDoc[key]; !ok && !isActivityField(key) {
+					delete(slowDoc, key)
+				}
+			}
+			normalizeObjects(slowDoc)
+
+			if !reflect.DeepEqual(fastDoc, slowDoc) {
+				t.Errorf("fast path differs from expansion\nfast: %s\nslow: %s", fastBytes, slowBytes)
+			}
+		})
+	}
+}
+
+// isActivityField reports whether an expanded property is kept by
+// models.Activity
+func isActivityField(key string) bool {
+	switch key {
+	case "@id", "@type",
+		"https://www.w3.org/ns/activitystreams#object",
+		"https://www.w3.org/ns/activitystreams#actor",
+		"https://www.w3.org/ns/activitystreams#target",
+		"https://www.w3.org/ns/activitystreams#result",
+		"https://www.w3.org/ns/activitystreams#origin",
+		"https://www.w3.org/ns/activitystreams#instrument",
+		"https://www.w3.org/ns/activitystreams#to",
+		"https://www.w3.org/ns/activitystreams#cc",
+		"https://www.w3.org/ns/activitystreams#audience",
+		"https://www.w3.org/ns/activitystreams#attributedTo":
+		return true
+	default:
+		return false
+	}
+}
+
+// normalizeObjects strips the properties models.Activity does not keep
+// from embedded objects
+func normalizeObjects(doc map[string]interface{}) {
+	objects, _ := doc["https://www.w3.org/ns/activitystreams#object"].([]interface{})
+	for _, object := range objects {
+		node, ok := object.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for key := range node {
+			if !isActivityField(key) {
+				delete(node, key)
+			}
+		}
+		normalizeObjects(node)
+	}
+}
+
+func TestCompactActivityFallsBack(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		name string
+		doc  string
+	}{
+		{"no context", `{"id": "https://example.org/1", "type": "Follow"}`},
+		{"unknown context", `{"@context": "https://example.org/context", "id": "https://example.org/1", "type": "Follow"}`},
+		{"security context only", `{"@context": "https://w3id.org/security/v1", "id": "https://example.org/1", "type": "Follow"}`},
+		{"redefined term", `{"@context": ["https://www.w3.org/ns/activitystreams", {"object": "https://example.org/ns#object"}], "id": "https://example.org/1", "type": "Follow"}`},
+		{"context vocab", `{"@context": ["https://www.w3.org/ns/activitystreams", {"@vocab": "https://example.org/ns#"}], "id": "https://example.org/1", "type": "Follow"}`},
+		{"compact iri key", `{"@context": "https://www.w3.org/ns/activitystreams", "id": "https://example.org/1", "type": "Follow", "as:object": "https://example.org/2"}`},
+		{"alias of an as property", `{"@context": ["https://www.w3.org/ns/activitystreams", {"obj": "https://www.w3.org/ns/activitystreams#object"}], "id": "https://example.org/1", "type": "Follow", "obj": "https://example.org/2"}`},
+		{"keyword alias", `{"@context": ["https://www.w3.org/ns/activitystreams", {"ident": "@id"}], "ident": "https://example.org/1", "type": "Follow"}`},
+		{"compact iri alias", `{"@context": ["https://www.w3.org/ns/activitystreams", {"who": "as:actor"}], "id": "https://example.org/1", "type": "Follow", "who": "https://example.org/actor"}`},
+		{"alias through an @id", `{"@context": ["https://www.w3.org/ns/activitystreams", {"who": {"@id": "as:actor", "@type": "@id"}}], "id": "https://example.org/1", "type": "Follow", "who": "https://example.org/actor"}`},
+		{"alias through an inline prefix", `{"@context": ["https://www.w3.org/ns/activitystreams", {"w3": "https://www.w3.org/ns/", "who": "w3:activitystreams#actor"}], "id": "https://example.org/1", "type": "Follow", "who": "https://example.org/actor"}`},
+		{"alias of a term", `{"@context": ["https://www.w3.org/ns/activitystreams", {"obj": "object"}], "id": "https://example.org/1", "type": "Follow", "obj": "https://example.org/2"}`},
+		{"reverse property", `{"@context": ["https://www.w3.org/ns/activitystreams", {"actorOf": {"@reverse": "as:actor"}}], "id": "https://example.org/1", "type": "Follow"}`},
+		{"type from another context", `{"@context": ["https://www.w3.org/ns/activitystreams", {"EmojiReact": "http://litepub.social/ns#EmojiReact"}], "id": "https://example.org/1", "type": "EmojiReact"}`},
+		{"compact iri type", `{"@context": "https://www.w3.org/ns/activitystreams", "id": "https://example.org/1", "type": "as:Follow"}`},
+		{"relative id", `{"@context": "https://www.w3.org/ns/activitystreams", "id": "/1", "type": "Follow"}`},
+		{"embedded actor", `{"@context": "https://www.w3.org/ns/activitystreams", "id": "https://example.org/1", "type": "Follow", "actor": {"id": "https://example.org/actor"}}`},
+		{"graph", `{"@context": "https://www.w3.org/ns/activitystreams", "@graph": []}`},
+		{"null id", nullIDFollowJSON},
+		{"no type", `{"@context": "https://www.w3.org/ns/activitystreams", "id": "https://example.org/1"}`},
+	}
+
+	for _, tt := range tests {
+		var raw map[string]interface{}
+		err := json.Unmarshal([]byte(tt.doc), &raw)
+		if err != nil {
+			t.Fatalf("%s: could not unmarshal document: %v", tt.name, err)
+		}
+
+		if _, ok := compactActivity(raw); ok {
+			t.Errorf("%s: expected the fast path to fall back to expansion", tt.name)
+		}
+	}
+}
+
+func benchmarkParse(b *testing.B, doc string, fast bool) {
+	i := NewInbox(nil, "https", "www.example.com", ldcontext.NewLoader(), nil, nil, subscribedRegistry())
+
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		var raw map[string]interface{}
+		err := json.Unmarshal([]byte(doc), &raw)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		if fast {
+			_, err = i.parseActivity(raw)
+		} else {
+			_, err = expandAndHydrate(i, raw)
+		}
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// expandAndHydrate parses raw the way the inbox did before the fast path
+func expandAndHydrate(i *Inbox, raw map[string]interface{}) (interface{}, error) {
+	expanded, err := i.proc.Expand(raw, i.opts)
+	if err != nil {
+		return nil, err
+	}
+	return hydrateActivity(expanded[0].(map[string]interface{}))
+}
+
+func BenchmarkParseCreateFastPath(b *testing.B) {
+	benchmarkParse(b, mastodonCreateJSON, true)
+}
+
+func BenchmarkParseCreateExpansion(b *testing.B) {
+	benchmarkParse(b, mastodonCreateJSON, false)
+}
+
+func BenchmarkParseFollowFastPath(b *testing.B) {
+	benchmarkParse(b, followJSON, true)
+}
+
+func BenchmarkParseFollowExpansion(b *testing.B) {
+	benchmarkParse(b, followJSON, false)
+}
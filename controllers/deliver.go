@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/Koshroy/turnover/httpsig"
+	"github.com/Koshroy/turnover/tasks"
+)
+
+// defaultDeliveryTimeout bounds deliveries made without a configured client
+const defaultDeliveryTimeout = 30 * time.Second
+
+// Deliverer enqueues signed deliveries of activities to remote inboxes
+type Deliverer struct {
+	queuer  tasks.Queuer
+	storer  tasks.Storer
+	client  *http.Client
+	signer  tasks.RequestSigner
+	schemes *httpsig.HostSchemes
+}
+
+// NewDeliverer creates a new Deliverer. A nil signer sends deliveries unsigned
+func NewDeliverer(
+	queuer tasks.Queuer,
+	storer tasks.Storer,
+	client *http.Client,
+	signer tasks.RequestSigner,
+	schemes *httpsig.HostSchemes,
+) *Deliverer {
+	return &Deliverer{
+		queuer:  queuer,
+		storer:  storer,
+		client:  client,
+		signer:  signer,
+		schemes: schemes,
+	}
+}
+
+// Deliver stores and enqueues a Forward task which delivers activity to target
+func (d *Deliverer) Deliver(activity []byte, target url.URL) error {
+	taskID, err := tasks.NewTaskID()
+	if err != nil {
+		return fmt.Errorf("error generating task ID: %v", err)
+	}
+
+	forward := &tasks.Forward{
+		TaskID:   taskID,
+		Activity: activity,
+		Target:   target,
+		Client:   d.client,
+		Signer:   d.signer,
+		Schemes:  d.schemes,
+	}
+
+	if !d.storer.Put(forward, taskID) {
+		return fmt.Errorf("could not store task information")
+	}
+
+	if !d.queuer.Enqueue(taskID) {
+		return fmt.Errorf("could not enqueue forward activity")
+	}
+
+	return nil
+}
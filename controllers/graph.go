@@ -0,0 +1,137 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+)
+
+// objectIRI is the expanded ActivityStreams object property
+const objectIRI = "https://www.w3.org/ns/activitystreams#object"
+
+// ErrAmbiguousDocument is returned when a document does not hold exactly
+// one top level activity
+var ErrAmbiguousDocument = errors.New("document must hold exactly one activity")
+
+// rootActivity picks the activity a document delivers among its expanded
+// top level nodes, which come from a @graph or a top level array. The
+// activity is the one node of a supported activity type which no other
+// node holds as its object. The other nodes are only kept as its object
+// graph, embedded where the activity and its objects reference them, so
+// they are never relayed as activities of their own
+func rootActivity(nodes []interface{}) (map[string]interface{}, error) {
+	byID := make(map[string]map[string]interface{}, len(nodes))
+	referenced := make(map[string]bool)
+	var activityNodes []map[string]interface{}
+
+	for _, n := range nodes {
+		node, ok := n.(map[string]interface{})
+		if !ok {
+			return nil, ErrMalformedActivity
+		}
+		if _, ok := node["@graph"]; ok {
+			return nil, fmt.Errorf("%w: named graphs are not supported", ErrAmbiguousDocument)
+		}
+
+		if id, _ := node["@id"].(string); id != "" {
+			if _, ok := byID[id]; ok {
+				return nil, fmt.Errorf("%w: node %s appears more than once", ErrAmbiguousDocument, id)
+			}
+			byID[id] = node
+		}
+
+		if isActivityNode(node) {
+			activityNodes = append(activityNodes, node)
+		}
+		collectObjectIDs(node, referenced)
+	}
+
+	if len(activityNodes) == 0 {
+		if len(nodes) == 1 {
+			// leave it to validation to explain why the node is not an
+			// activity
+			return nodes[0].(map[string]interface{}), nil
+		}
+		return nil, ErrUnsupportedActivityType
+	}
+
+	var roots []map[string]interface{}
+	for _, node := range activityNodes {
+		if id, _ := node["@id"].(string); id == "" || !referenced[id] {
+			roots = append(roots, node)
+		}
+	}
+	if len(roots) != 1 {
+		return nil, fmt.Errorf("%w: found %d top level activities", ErrAmbiguousDocument, len(roots))
+	}
+
+	root := roots[0]
+	embedObjects(root, byID, make(map[string]bool))
+	return root, nil
+}
+
+// isActivityNode reports whether an expanded node has a supported activity
+// type
+func isActivityNode(node map[string]interface{}) bool {
+	types, _ := node["@type"].([]interface{})
+	for _, t := range types {
+		if typeIRI, ok := t.(string); ok {
+			if _, ok := activityTypes[typeIRI]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// collectObjectIDs records the IRIs of the objects of node and of its
+// embedded objects in ids
+func collectObjectIDs(node map[string]interface{}, ids map[string]bool) {
+	objects, _ := node[objectIRI].([]interface{})
+	for _, o := range objects {
+		object, ok := o.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if id, _ := object["@id"].(string); id != "" {
+			ids[id] = true
+		}
+		collectObjectIDs(object, ids)
+	}
+}
+
+// embedObjects replaces references to the objects of node by the nodes of
+// the document they refer to. visiting guards against reference cycles
+func embedObjects(node map[string]interface{}, byID map[string]map[string]interface{}, visiting map[string]bool) {
+	if id, _ := node["@id"].(string); id != "" {
+		visiting[id] = true
+		defer delete(visiting, id)
+	}
+
+	objects, _ := node[objectIRI].([]interface{})
+	for idx, o := range objects {
+		object, ok := o.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		id, _ := object["@id"].(string)
+		if target, ok := byID[id]; ok && len(object) == 1 && !visiting[id] {
+			object = copyNode(target)
+			objects[idx] = object
+		}
+		embedObjects(object, byID, visiting)
+	}
+}
+
+// copyNode returns a shallow copy of node, so embedding it does not change
+// the document it came from
+func copyNode(node map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(node))
+	for key, value := range node {
+		copied[key] = value
+	}
+	if objects, ok := node[objectIRI].([]interface{}); ok {
+		copied[objectIRI] = append([]interface{}(nil), objects...)
+	}
+	return copied
+}
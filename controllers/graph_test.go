@@ -0,0 +1,165 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Koshroy/turnover/ldcontext"
+)
+
+const graphCreateJSON = `{
+    "@context": "https://www.w3.org/ns/activitystreams",
+    "@graph": [
+        {
+            "id": "https://sally.example.org/notes/1",
+            "type": "Note",
+            "attributedTo": "https://sally.example.org",
+            "to": "https://www.w3.org/ns/activitystreams#Public"
+        },
+        {
+            "id": "https://sally.example.org/activities/1",
+            "type": "Create",
+            "actor": "https://sally.example.org",
+            "object": "https://sally.example.org/notes/1",
+            "to": "https://www.w3.org/ns/activitystreams#Public"
+        }
+    ]
+}
+`
+
+const arrayAnnounceJSON = `[
+    {
+        "@context": "https://www.w3.org/ns/activitystreams",
+        "id": "https://sally.example.org/activities/2",
+        "type": "Announce",
+        "actor": "https://sally.example.org",
+        "object": "https://sally.example.org/activities/1",
+        "to": "https://www.w3.org/ns/activitystreams#Public"
+    },
+    {
+        "@context": "https://www.w3.org/ns/activitystreams",
+        "id": "https://sally.example.org/activities/1",
+        "type": "Create",
+        "actor": "https://sally.example.org",
+        "object": "https://sally.example.org/notes/1",
+        "to": "https://www.w3.org/ns/activitystreams#Public"
+    }
+]
+`
+
+const arrayTwoCreatesJSON = `[
+    {
+        "@context": "https://www.w3.org/ns/activitystreams",
+        "id": "https://sally.example.org/activities/1",
+        "type": "Create",
+        "actor": "https://sally.example.org",
+        "object": "https://sally.example.org/notes/1"
+    },
+    {
+        "@context": "https://www.w3.org/ns/activitystreams",
+        "id": "https://sally.example.org/activities/2",
+        "type": "Create",
+        "actor": "https://sally.example.org",
+        "object": "https://sally.example.org/notes/2"
+    }
+]
+`
+
+const graphCycleJSON = `{
+    "@context": "https://www.w3.org/ns/activitystreams",
+    "@graph": [
+        {
+            "id": "https://sally.example.org/activities/1",
+            "type": "Announce",
+            "actor": "https://sally.example.org",
+            "object": "https://sally.example.org/activities/2"
+        },
+        {
+            "id": "https://sally.example.org/activities/2",
+            "type": "Announce",
+            "actor": "https://sally.example.org",
+            "object": "https://sally.example.org/activities/1"
+        }
+    ]
+}
+`
+
+const graphNotesJSON = `{
+    "@context": "https://www.w3.org/ns/activitystreams",
+    "@graph": [
+        {"id": "https://sally.example.org/notes/1", "type": "Note"},
+        {"id": "https://sally.example.org/notes/2", "type": "Note"}
+    ]
+}
+`
+
+func TestParseActivityGraphs(t *testing.T) {
+	t.Parallel()
+
+	i := NewInbox([]string{}, "https", "www.example.com", ldcontext.NewLoader(), nil, nil, subscribedRegistry())
+
+	create := mustParse(t, i, graphCreateJSON)
+	if create.ID == nil || *create.ID != "https://sally.example.org/activities/1" {
+		t.Fatalf("expected the Create to be picked got %v", create.ID)
+	}
+	if len(create.Object) != 1 || len(create.Object[0].Type) != 1 || create.Object[0].Type[0] != "https://www.w3.org/ns/activitystreams#Note" {
+		t.Errorf("expected the note to be embedded as the object got %+v", create.Object)
+	}
+
+	announce := mustParse(t, i, arrayAnnounceJSON)
+	if announce.ID == nil || *announce.ID != "https://sally.example.org/activities/2" {
+		t.Fatalf("expected the Announce to be picked got %v", announce.ID)
+	}
+	if len(announce.Object) != 1 || len(announce.Object[0].Object) != 1 {
+		t.Errorf("expected the Create to be embedded with its object got %+v", announce.Object)
+	}
+
+	var tests = []struct {
+		name string
+		doc  string
+		want error
+	}{
+		{"two activities", arrayTwoCreatesJSON, ErrAmbiguousDocument},
+		{"activities referencing each other", graphCycleJSON, ErrAmbiguousDocument},
+		{"no activity", graphNotesJSON, ErrUnsupportedActivityType},
+		{"empty graph", `{"@context": "https://www.w3.org/ns/activitystreams", "@graph": []}`, ErrMalformedActivity},
+		{"scalar", `"https://sally.example.org/activities/1"`, ErrMalformedActivity},
+	}
+
+	for _, tt := range tests {
+		var raw interface{}
+		err := json.Unmarshal([]byte(tt.doc), &raw)
+		if err != nil {
+			t.Fatalf("%s: could not unmarshal document: %v", tt.name, err)
+		}
+
+		_, err = i.parseActivity(raw)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: expected %v got %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+func TestInboxRelaysOnlyTheRootActivity(t *testing.T) {
+	t.Parallel()
+
+	q := newMockQueuer()
+	s := newMockStorer()
+	i := NewInbox([]string{}, "https", "www.example.com", ldcontext.NewLoader(), q, s, subscribedRegistry())
+
+	testResp(t, i, q, s, []respTest{
+		{graphCreateJSON, http.StatusAccepted, 1, "graph_create"},
+		{arrayAnnounceJSON, http.StatusAccepted, 1, "array_announce"},
+		{arrayTwoCreatesJSON, http.StatusBadRequest, 0, "array_two_creates"},
+	})
+
+	w := httptest.NewRecorder()
+	i.ServeHTTP(w, httptest.NewRequest("POST", "/", strings.NewReader(graphNotesJSON)))
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("expected a graph without activities to be rejected with 415 got %d", w.Code)
+	}
+}
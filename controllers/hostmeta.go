@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	"encoding/xml"
+	"log"
+	"net/http"
+
+	"github.com/Koshroy/turnover/middleware"
+)
+
+const xrdNamespace = "http://docs.oasis-open.org/ns/xri/xrd-1.0"
+const xrdContentType = "application/xrd+xml"
+
+type xrdLink struct {
+	Rel      string `xml:"rel,attr" json:"rel"`
+	Type     string `xml:"type,attr,omitempty" json:"type,omitempty"`
+	Template string `xml:"template,attr" json:"template"`
+}
+
+type xrd struct {
+	XMLName xml.Name  `xml:"XRD" json:"-"`
+	XMLNS   string    `xml:"xmlns,attr" json:"-"`
+	Links   []xrdLink `xml:"Link" json:"links"`
+}
+
+// HostMeta is the controller logic for the /.well-known/host-meta endpoints,
+// which point legacy WebFinger clients at the WebFinger template
+type HostMeta struct {
+	scheme, domain string
+}
+
+// NewHostMeta creates a new HostMeta controller
+func NewHostMeta(scheme, domain string) HostMeta {
+	return HostMeta{
+		scheme: scheme,
+		domain: domain,
+	}
+}
+
+func (h HostMeta) document() xrd {
+	return xrd{
+		XMLNS: xrdNamespace,
+		Links: []xrdLink{
+			{
+				Rel:      "lrdd",
+				Type:     "application/jrd+json",
+				Template: h.scheme + "://" + h.domain + "/.well-known/webfinger?resource={uri}",
+			},
+		},
+	}
+}
+
+// ServeHTTP serves host-meta as XRD, or as JSON when the client prefers
+// JSON
+func (h HostMeta) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Vary", "Accept")
+	switch middleware.Preferred(r, xrdContentType, "application/xml", "application/json", "application/jrd+json") {
+	case "application/json", "application/jrd+json":
+		h.ServeJSON(w, r)
+		return
+	}
+
+	b, err := xml.Marshal(h.document())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", xrdContentType+"; charset=utf-8")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	_, err = w.Write(append([]byte(xml.Header), b...))
+	if err != nil {
+		log.Printf("error writing response: %v\n", err)
+	}
+}
+
+// ServeJSON serves host-meta as JSON, for /.well-known/host-meta.json
+func (h HostMeta) ServeJSON(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	writeJSON(w, "application/json", h.document())
+}
@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const hostMetaTemplate = "https://www.example.com/.well-known/webfinger?resource={uri}"
+
+func TestHostMetaXRD(t *testing.T) {
+	t.Parallel()
+
+	w := httptest.NewRecorder()
+	NewHostMeta("https", "www.example.com").ServeHTTP(w, httptest.NewRequest("GET", "/.well-known/host-meta", nil))
+
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "application/xrd+xml") {
+		t.Errorf("expected application/xrd+xml got %s", w.Header().Get("Content-Type"))
+	}
+
+	var doc xrd
+	err := xml.Unmarshal(w.Body.Bytes(), &doc)
+	if err != nil {
+		t.Fatalf("could not unmarshal XRD: %v", err)
+	}
+
+	if doc.XMLName.Space != xrdNamespace {
+		t.Errorf("expected XRD namespace got %s", doc.XMLName.Space)
+	}
+
+	if len(doc.Links) != 1 || doc.Links[0].Rel != "lrdd" || doc.Links[0].Template != hostMetaTemplate {
+		t.Errorf("expected lrdd link to the WebFinger template got %+v", doc.Links)
+	}
+}
+
+func TestHostMetaJSON(t *testing.T) {
+	t.Parallel()
+
+	req := httptest.NewRequest("GET", "/.well-known/host-meta", nil)
+	req.Header.Set("Accept", "application/json")
+	w := httptest.NewRecorder()
+	NewHostMeta("https", "www.example.com").ServeHTTP(w, req)
+
+	if w.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("expected application/json got %s", w.Header().Get("Content-Type"))
+	}
+
+	var doc struct {
+		Links []map[string]string
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &doc)
+	if err != nil {
+		t.Fatalf("could not unmarshal host-meta JSON: %v", err)
+	}
+
+	if len(doc.Links) != 1 || doc.Links[0]["template"] != hostMetaTemplate {
+		t.Errorf("expected lrdd link to the WebFinger template got %v", doc.Links)
+	}
+}
+
+func TestHostMetaNegotiation(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		accept      string
+		contentType string
+	}{
+		{"application/xrd+xml;q=0.1, application/json", "application/json"},
+		{"application/jrd+json", "application/json"},
+		{"application/json;q=0.5, application/xrd+xml", xrdContentType},
+		{"application/xml, application/json", xrdContentType},
+		{"*/*", xrdContentType},
+	}
+
+	for _, tt := range tests {
+		var tt = tt
+		t.Run(tt.accept, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest("GET", "/.well-known/host-meta", nil)
+			req.Header.Set("Accept", tt.accept)
+			w := httptest.NewRecorder()
+			NewHostMeta("https", "www.example.com").ServeHTTP(w, req)
+
+			if !strings.HasPrefix(w.Header().Get("Content-Type"), tt.contentType) {
+				t.Errorf("expected %s got %s", tt.contentType, w.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
@@ -3,196 +3,566 @@ package controllers
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"net/url"
+	"strings"
+	"time"
 
+	"github.com/Koshroy/turnover/httpsig"
 	"github.com/Koshroy/turnover/models"
+	"github.com/Koshroy/turnover/seen"
+	"github.com/Koshroy/turnover/subscribers"
 	"github.com/Koshroy/turnover/tasks"
+	"github.com/gofrs/uuid"
 	"github.com/piprate/json-gold/ld"
 )
 
-const maxActivitySz = 16 * (1 << 20) // 16 MB
 const followIRI = "https://www.w3.org/ns/activitystreams#Follow"
 const unfollowIRI = "https://www.w3.org/ns/activitystreams#Unfollow"
 const createIRI = "https://www.w3.org/ns/activitystreams#Create"
 const readIRI = "https://www.w3.org/ns/activitystreams#Read"
 const updateIRI = "https://www.w3.org/ns/activitystreams#Update"
 const deleteIRI = "https://www.w3.org/ns/activitystreams#Delete"
+const undoIRI = "https://www.w3.org/ns/activitystreams#Undo"
 
-// ErrUnsupportedActivityType is returned when the activity
-// contains a type that is not Follow, Create, Read, Update, Delete, or Unfollow
-// or is a multi-type activity
+// ErrUnsupportedActivityType is returned when the activity contains a type
+// without a policy, or is a multi-type activity whose types have different
+// policies
 var ErrUnsupportedActivityType = errors.New("unsupported activity type")
 
 // ErrNullIDUnsupported is returned when the ID is specifically missing or set to null
 var ErrNullIDUnsupported = errors.New("activity id cannot be null or missing")
 
-// ErrIncorrectFollow is returned when a non-inbox endpoint is attempted to be followed
+// ErrMalformedActivity is returned when the request body is not a JSON
+// object or is not valid JSON-LD
+var ErrMalformedActivity = errors.New("malformed activity")
+
+// ErrIncorrectFollow is returned when something other than the relay is
+// attempted to be followed
 var ErrIncorrectFollow = errors.New("cannot follow this resource")
 
 // Inbox is a controller that controls the Inbox endpoint
 type Inbox struct {
 	whitelist      []string
-	loader         *ld.RFC7324CachingDocumentLoader
+	loader         ld.DocumentLoader
 	proc           *ld.JsonLdProcessor
 	opts           *ld.JsonLdOptions
 	scheme, domain string
-	queuer         tasks.Queuer
-	storer         tasks.Storer
+	registry       subscribers.Registry
+	deliverer      *Deliverer
+	verifier       *httpsig.Verifier
+	publisher      *Publisher
+	policies       Policies
+	fetchObject    httpsig.DocumentFetcher
+	seen           seen.Set
+	limits         Limits
+	expansions     chan struct{}
 }
 
-// NewInbox creates a new Inbox controller
+// NewInbox creates a new Inbox controller. JSON-LD contexts of incoming
+// activities are loaded from loader, and relayed activities are queued
+// with queuer and storer as unsigned deliveries until WithDeliverer is used
 func NewInbox(
 	whitelist []string,
 	scheme, domain string,
-	client *http.Client,
+	loader ld.DocumentLoader,
 	queuer tasks.Queuer,
 	storer tasks.Storer,
+	registry subscribers.Registry,
 ) *Inbox {
-	loader := ld.NewRFC7324CachingDocumentLoader(client)
 	opts := ld.NewJsonLdOptions("")
 	opts.DocumentLoader = loader
+	limits := DefaultLimits()
 
 	return &Inbox{
-		whitelist: whitelist,
-		loader:    loader,
-		proc:      ld.NewJsonLdProcessor(),
-		opts:      opts,
-		scheme:    scheme,
-		domain:    domain,
-		queuer:    queuer,
-		storer:    storer,
+		whitelist:  whitelist,
+		loader:     loader,
+		proc:       ld.NewJsonLdProcessor(),
+		opts:       opts,
+		scheme:     scheme,
+		domain:     domain,
+		registry:   registry,
+		deliverer:  NewDeliverer(queuer, storer, &http.Client{Timeout: defaultDeliveryTimeout}, nil, nil),
+		policies:   DefaultPolicies(),
+		limits:     limits,
+		expansions: newSemaphore(limits.Expansions),
 	}
 }
 
+// WithVerifier makes the Inbox require a valid HTTP signature on every request
+func (i *Inbox) WithVerifier(verifier *httpsig.Verifier) *Inbox {
+	i.verifier = verifier
+	return i
+}
+
+// WithPublisher makes the Inbox answer follows with an Accept or Reject
+func (i *Inbox) WithPublisher(publisher *Publisher) *Inbox {
+	i.publisher = publisher
+	return i
+}
+
+// WithDeliverer sets how relayed activities are delivered to subscribers
+func (i *Inbox) WithDeliverer(deliverer *Deliverer) *Inbox {
+	i.deliverer = deliverer
+	return i
+}
+
+// WithObjectFetcher lets the Inbox confirm embedded objects from another
+// origin by fetching them from their own origin. Without it such objects
+// are rejected. Fetches run while the request is handled, so each object
+// missing from the cache can add up to the fetch timeout to the response
+func (i *Inbox) WithObjectFetcher(fetch httpsig.DocumentFetcher) *Inbox {
+	i.fetchObject = fetch
+	return i
+}
+
+// WithSeenSet makes the Inbox remember the IDs of the activities it
+// accepts, and accept redelivered activities without processing them again
+func (i *Inbox) WithSeenSet(set seen.Set) *Inbox {
+	i.seen = set
+	return i
+}
+
+// WithLimits sets the resources the Inbox may spend on a request
+func (i *Inbox) WithLimits(limits Limits) *Inbox {
+	i.limits = limits
+	i.expansions = newSemaphore(limits.Expansions)
+	return i
+}
+
+// WithPolicies sets how the Inbox handles each activity type
+func (i *Inbox) WithPolicies(policies Policies) *Inbox {
+	i.policies = policies
+	return i
+}
+
 func (i Inbox) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	body := http.MaxBytesReader(w, r.Body, maxActivitySz)
+	body := r.Body
+	if i.limits.BodySize > 0 {
+		body = http.MaxBytesReader(w, r.Body, i.limits.BodySize)
+	}
 	bodyBytes, err := ioutil.ReadAll(body)
 	if err != nil {
-		w.WriteHeader(http.StatusNotAcceptable)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			err = fmt.Errorf("%w: more than %d bytes", ErrActivityTooLarge, i.limits.BodySize)
+			writeProblem(w, http.StatusRequestEntityTooLarge, err.Error())
+			return
+		}
+		writeProblem(w, http.StatusBadRequest, "could not read request body")
 		return
 	}
 
-	var raw map[string]interface{}
-	err = json.Unmarshal(bodyBytes, &raw)
+	status, err := i.receive(r, bodyBytes)
 	if err != nil {
-		w.WriteHeader(http.StatusUnsupportedMediaType)
+		writeProblem(w, status, err.Error())
 		return
 	}
+	w.WriteHeader(status)
+}
 
-	expanded, err := i.proc.Expand(raw, i.opts)
+// receive handles a delivered activity and returns the status to respond
+// with, along with an error explaining it for failures
+func (i Inbox) receive(r *http.Request, bodyBytes []byte) (int, error) {
+	keyOrigin := ""
+	if i.verifier != nil {
+		keyID, _, err := i.verifier.Verify(r, bodyBytes)
+		if err != nil {
+			return http.StatusUnauthorized, err
+		}
+		keyOrigin = origin(keyID)
+	}
+
+	err := checkDepth(bodyBytes, i.limits.Depth)
 	if err != nil {
-		w.WriteHeader(http.StatusUnsupportedMediaType)
-		return
+		return http.StatusBadRequest, err
 	}
 
-	followTypes := false
-	hydratedActivities := make([]*models.Activity, 0)
-	for _, rawActivity := range expanded {
-		activity, typeOk := rawActivity.(map[string]interface{})
-		if !typeOk {
-			w.WriteHeader(http.StatusUnsupportedMediaType)
-			_, writeErr := w.Write([]byte(err.Error()))
-			if writeErr != nil {
-				log.Printf("error writing response: %v\n", err)
+	var raw interface{}
+	err = json.Unmarshal(bodyBytes, &raw)
+	if err != nil {
+		return http.StatusBadRequest, fmt.Errorf("%w: %v", ErrMalformedActivity, err)
+	}
+
+	activity, err := i.parseActivity(raw)
+	if err != nil {
+		return parseStatus(err), err
+	}
+
+	policy, err := i.policies.For(activity)
+	if err != nil {
+		return http.StatusUnsupportedMediaType, err
+	}
+
+	err = i.checkOrigins(activity, keyOrigin)
+	if err != nil {
+		return http.StatusForbidden, err
+	}
+
+	for _, activityType := range activity.Type {
+		switch activityType {
+		case followIRI, unfollowIRI:
+			if !i.followsRelay(activity) {
+				return http.StatusBadRequest, fmt.Errorf("%w: follows and unfollows can only be of %s, %s or %s",
+					ErrIncorrectFollow, i.routeURL("/actor", "").String(), i.routeURL("/inbox", "").String(), publicIRI)
 			}
-			return
-		}
-		hydrated, err := hydrateActivity(activity)
-		if err != nil {
-			w.WriteHeader(http.StatusUnsupportedMediaType)
-			_, writeErr := w.Write([]byte(err.Error()))
-			if writeErr != nil {
-				log.Printf("error writing response: %v\n", err)
+		case undoIRI:
+			if !i.undoesFollow(activity) {
+				return http.StatusBadRequest, fmt.Errorf("%w: only follows of the relay by the same actor can be undone",
+					ErrIncorrectFollow)
 			}
-			return
 		}
+	}
 
-		myInboxURI := i.routeURL("/inbox", "").String()
-		for _, hydratedType := range hydrated.Type {
-			if hydratedType == followIRI || hydratedType == unfollowIRI {
-				followTypes = true
-				for _, objectActivity := range hydrated.Object {
-					if objectActivity.ID == nil ||
-						(*objectActivity.ID != myInboxURI) {
-						w.WriteHeader(http.StatusMethodNotAllowed)
-						_, err := w.Write([]byte("follows and unfollows can only be to " + myInboxURI))
-						if err != nil {
-							log.Printf("error writing response: %v\n", err)
-						}
-						return
-					}
-				}
-			}
+	inboxMetrics.Add("activities", 1)
+	if !i.firstDelivery(activity) {
+		inboxMetrics.Add("duplicates", 1)
+		log.Printf("already received activity %s, ignoring\n", *activity.ID)
+		return http.StatusAccepted, nil
+	}
+
+	switch policy {
+	case PolicyIgnore:
+		log.Printf("ignoring %s activity %s\n", strings.Join(activity.Type, ", "), *activity.ID)
+		return http.StatusAccepted, nil
+	case PolicyLocal:
+		i.processLocally(activity)
+		return http.StatusAccepted, nil
+	}
+
+	resolved := i.resolveObjects(activity)
+	if deletesLiveObject(activity, resolved) {
+		auditDrop(activity, "deleted object is still served by its origin")
+		return http.StatusAccepted, nil
+	}
+
+	if !isPublic(resolved) {
+		auditDrop(activity, "not addressed to the public")
+		return http.StatusAccepted, nil
+	}
+
+	err = i.forward(activity, bodyBytes)
+	if err != nil {
+		log.Printf("could not forward activity: %v\n", err)
+		i.forget(activity)
+		return http.StatusInternalServerError, err
+	}
+
+	return http.StatusAccepted, nil
+}
+
+// forward queues deliveries of the body of a relayed activity to the inbox
+// of every subscriber, except subscribers on the host the activity comes
+// from. Deliveries which cannot be queued are logged without stopping the
+// others, and forward only fails when none could be queued, since a
+// redelivery of the activity would reach the other subscribers again
+func (i Inbox) forward(activity *models.Activity, body []byte) error {
+	actorID := nodeID(activity.Actor)
+	delivered := make(map[string]bool)
+	var errs []error
+	for _, sub := range i.registry.List() {
+		if sub.Inbox == "" || delivered[sub.Inbox] || sameHost(sub.Actor, actorID) {
+			continue
+		}
+
+		target, err := url.Parse(sub.Inbox)
+		if err != nil || !target.IsAbs() {
+			log.Printf("invalid inbox %s for subscriber %s, skipping\n", sub.Inbox, sub.Actor)
+			continue
 		}
-		hydratedActivities = append(hydratedActivities, hydrated)
+
+		err = i.deliverer.Deliver(body, *target)
+		if err != nil {
+			log.Printf("could not queue delivery to %s: %v\n", sub.Inbox, err)
+			errs = append(errs, err)
+			continue
+		}
+		delivered[sub.Inbox] = true
 	}
 
-	if !followTypes {
-		for _, activity := range hydratedActivities {
-			taskID, err := tasks.NewTaskID()
-			if err != nil {
-				log.Printf("error generating task ID: %v\n", err)
-				w.WriteHeader(http.StatusInternalServerError)
-				_, writeErr := w.Write([]byte(err.Error()))
-				if writeErr != nil {
-					log.Printf("error writing response: %v\n", writeErr)
-				}
-				continue
-			}
+	if len(delivered) == 0 && len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	return nil
+}
 
-			activityBytes, err := json.Marshal(activity)
-			if err != nil {
-				log.Printf("error marshalling activity: %v\n", err)
-				w.WriteHeader(http.StatusInternalServerError)
-				_, writeErr := w.Write([]byte(err.Error()))
-				if writeErr != nil {
-					log.Printf("error writing response: %v\n", writeErr)
-				}
-				continue
-			}
+// parseStatus returns the status for an activity which could not be parsed
+func parseStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrUnsupportedActivityType):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, ErrTooManyNodes):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, ErrExpansionTimeout):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusBadRequest
+	}
+}
 
-			// TODO: add forward targets
-			forward := &tasks.Forward{
-				TaskID:   taskID,
-				Activity: activityBytes,
-				Client:   http.DefaultClient,
+// firstDelivery records the ID of activity as seen, and reports false when
+// it was already seen. Activities without an ID cannot be told apart and
+// are always processed
+func (i Inbox) firstDelivery(activity *models.Activity) bool {
+	if i.seen == nil || activity.ID == nil || *activity.ID == "" {
+		return true
+	}
+	return i.seen.Add(*activity.ID, time.Now())
+}
+
+// forget removes activity from the seen-set after it failed to be
+// processed, so a redelivery is processed again
+func (i Inbox) forget(activity *models.Activity) {
+	if i.seen != nil && activity.ID != nil && *activity.ID != "" {
+		i.seen.Remove(*activity.ID)
+	}
+}
+
+// updateSubscription adds or removes the actor of a Follow, Unfollow or
+// Undo of a Follow from the subscriber registry. Follows are answered with
+// an Accept, or a Reject when the actor is not whitelisted, and the Accept
+// is retracted when the subscriber leaves. The inbox comes
+// from the embedded actor or is resolved from the actor's origin, and
+// Follows from actors without a known inbox are ignored, since neither the
+// answer nor relayed activities could reach them
+func (i Inbox) updateSubscription(activity *models.Activity) {
+	actorID := nodeID(activity.Actor)
+	if actorID == "" {
+		log.Println("follow activity has no actor, ignoring")
+		return
+	}
+
+	actorInbox := nodeID(nodeProperty(activity.Actor, ldpInboxIRI))
+	if actorInbox != "" && origin(actorInbox) != origin(actorID) {
+		log.Printf("actor %s has an inbox on another origin, ignoring it\n", actorID)
+		actorInbox = ""
+	}
+	if actorInbox == "" {
+		actorInbox = i.resolveInbox(actorID)
+	}
+	for _, activityType := range activity.Type {
+		switch activityType {
+		case followIRI:
+			if actorInbox == "" {
+				log.Printf("no known inbox for actor %s, ignoring follow\n", actorID)
+				return
+			}
+			if !i.whitelisted(actorID) {
+				log.Printf("rejecting follow from non-whitelisted actor %s\n", actorID)
+				i.answerFollow("Reject", activity, actorID, actorInbox)
+				return
 			}
 
-			success := i.storer.Put(forward, taskID)
-			if !success {
-				log.Println("error storing activity")
-				w.WriteHeader(http.StatusInternalServerError)
-				_, writeErr := w.Write([]byte("could not store task information"))
-				if writeErr != nil {
-					log.Printf("error writing response: %v\n", writeErr)
-				}
-				continue
+			accept := i.answerFollow("Accept", activity, actorID, actorInbox)
+			i.registry.Add(subscribers.Subscriber{
+				Actor:  actorID,
+				Inbox:  actorInbox,
+				Since:  time.Now(),
+				Accept: accept,
+			})
+		case unfollowIRI, undoIRI:
+			sub, ok := i.registry.Get(actorID)
+			i.registry.Remove(actorID)
+			if ok {
+				i.retractAccept(sub)
 			}
+		}
+	}
+}
+
+// followsRelay reports whether every object of a Follow or Unfollow
+// activity is followable
+func (i Inbox) followsRelay(activity *models.Activity) bool {
+	for _, object := range activity.Object {
+		if object.ID == nil || !i.followable(*object.ID) {
+			return false
+		}
+	}
+	return true
+}
+
+// undoesFollow reports whether every object of an Undo activity is an
+// embedded Follow of the relay by the actor of the Undo
+func (i Inbox) undoesFollow(activity *models.Activity) bool {
+	if len(activity.Object) == 0 {
+		return false
+	}
+
+	for idx := range activity.Object {
+		follow := &activity.Object[idx]
+		if !hasType(follow, followIRI) || len(follow.Object) == 0 || !i.followsRelay(follow) {
+			return false
+		}
+		if nodeID(follow.Actor) != nodeID(activity.Actor) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasType reports whether activity has the type IRI activityType
+func hasType(activity *models.Activity, activityType string) bool {
+	for _, t := range activity.Type {
+		if t == activityType {
+			return true
+		}
+	}
+	return false
+}
+
+// followable reports whether following objectID subscribes to the relay.
+// LitePub software follows the relay actor, Mastodon follows the public
+// collection and older clients follow the inbox
+func (i Inbox) followable(objectID string) bool {
+	switch objectID {
+	case i.routeURL("/actor", "").String(), i.routeURL("/inbox", "").String(), publicIRI:
+		return true
+	default:
+		return false
+	}
+}
+
+// whitelisted reports whether the host of actorID may subscribe
+func (i Inbox) whitelisted(actorID string) bool {
+	if len(i.whitelist) == 0 {
+		return true
+	}
+
+	actorURL, err := url.Parse(actorID)
+	if err != nil {
+		return false
+	}
+
+	for _, host := range i.whitelist {
+		if strings.EqualFold(host, actorURL.Host) {
+			return true
+		}
+	}
+	return false
+}
+
+// answerFollow publishes an Accept or Reject of a Follow activity and
+// returns its outbox ID, which is uuid.Nil when nothing was published
+func (i Inbox) answerFollow(answerType string, follow *models.Activity, actorID, actorInbox string) uuid.UUID {
+	if i.publisher == nil {
+		return uuid.Nil
+	}
+
+	object := map[string]interface{}{
+		"id":     *follow.ID,
+		"type":   "Follow",
+		"actor":  actorID,
+		"object": i.routeURL("/actor", "").String(),
+	}
+	if len(follow.Object) > 0 && follow.Object[0].ID != nil {
+		object["object"] = *follow.Object[0].ID
+	}
+
+	inboxes := make([]string, 0, 1)
+	if actorInbox != "" {
+		inboxes = append(inboxes, actorInbox)
+	}
+
+	id, _, err := i.publisher.publish(answerType, object, []string{actorID}, nil, inboxes)
+	if err != nil {
+		log.Printf("could not publish %s of follow from %s: %v\n", answerType, actorID, err)
+		return uuid.Nil
+	}
+	return id
+}
+
+// retractAccept retracts the Accept of the follow of a subscriber who
+// unfollowed, so it is served as a Tombstone
+func (i Inbox) retractAccept(sub subscribers.Subscriber) {
+	if i.publisher == nil || sub.Accept == uuid.Nil {
+		return
+	}
 
-			success = i.queuer.Enqueue(taskID)
-			if !success {
-				// TODO: should we delete the task storage if we could not enqueue it properly?
-				log.Println("error enqueuing forward activity")
-				w.WriteHeader(http.StatusInternalServerError)
-				_, writeErr := w.Write([]byte("could not enqueue forward activity"))
-				if writeErr != nil {
-					log.Printf("error writing response: %v\n", writeErr)
-				}
-				continue
+	inboxes := make([]string, 0, 1)
+	if sub.Inbox != "" {
+		inboxes = append(inboxes, sub.Inbox)
+	}
+
+	err := i.publisher.Retract(sub.Accept, []string{sub.Actor}, inboxes)
+	if err != nil {
+		log.Printf("could not retract accept of follow from %s: %v\n", sub.Actor, err)
+	}
+}
+
+// parseActivity returns the activity delivered in raw. Activities using
+// the plain ActivityStreams context are read directly, and anything else,
+// including @graph documents and top level arrays, goes through JSON-LD
+// expansion
+func (i Inbox) parseActivity(raw interface{}) (*models.Activity, error) {
+	switch raw.(type) {
+	case map[string]interface{}, []interface{}:
+	default:
+		return nil, fmt.Errorf("%w: expected a JSON object or array", ErrMalformedActivity)
+	}
+
+	err := checkNodes(raw, i.limits.Nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	if doc, ok := raw.(map[string]interface{}); ok {
+		if activity, ok := compactActivity(doc); ok {
+			err := validateActivity(activity)
+			if err != nil {
+				return nil, err
 			}
+			return activity, nil
 		}
 	}
+
+	expanded, err := i.expand(raw)
+	if errors.Is(err, ErrExpansionTimeout) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformedActivity, err)
+	}
+
+	err = checkNodes(expanded, i.limits.Nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(expanded) == 0 {
+		return nil, fmt.Errorf("%w: document is empty", ErrMalformedActivity)
+	}
+
+	root, err := rootActivity(expanded)
+	if err != nil {
+		return nil, err
+	}
+	return hydrateActivity(root)
 }
 
 func hydrateActivity(raw map[string]interface{}) (*models.Activity, error) {
+	activity, err := hydrateNode(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validateActivity(activity)
+	if err != nil {
+		return nil, err
+	}
+
+	return activity, nil
+}
+
+// hydrateNode builds an activity from an expanded node without checking it
+// is a supported activity
+func hydrateNode(raw map[string]interface{}) (*models.Activity, error) {
 	// This function is kinda jank because it marshals a raw interface
-	// then unmarshals it into a models.Activity type. There is probably
-	// a better way to do this, but rather than write complicated code to do that
-	// for now we'll do this. If this ends up being a speed bottleneck, we can
-	// write the bespoke logic for it
+	// then unmarshals it into a models.Activity type. Activities using the
+	// plain ActivityStreams context skip it through compactActivity, so
+	// this only runs for unusual documents
 
 	activityBytes, err := json.Marshal(raw)
 	if err != nil {
@@ -206,23 +576,23 @@ func hydrateActivity(raw map[string]interface{}) (*models.Activity, error) {
 		return nil, ErrUnsupportedActivityType
 	}
 
+	return &activity, nil
+}
+
+// validateActivity checks that activity is of a supported type and has an ID
+func validateActivity(activity *models.Activity) error {
 	for _, activityType := range activity.Type {
-		if activityType != followIRI &&
-			activityType != createIRI &&
-			activityType != updateIRI &&
-			activityType != readIRI &&
-			activityType != deleteIRI &&
-			activityType != unfollowIRI {
-			return nil, ErrUnsupportedActivityType
+		if _, ok := activityTypes[activityType]; !ok {
+			return ErrUnsupportedActivityType
 		}
 	}
 
 	// We disallow null IDs
 	if activity.ID == nil {
-		return nil, ErrNullIDUnsupported
+		return ErrNullIDUnsupported
 	}
 
-	return &activity, nil
+	return nil
 }
 
 func (i Inbox) routeURL(path, fragment string) *url.URL {
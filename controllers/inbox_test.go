@@ -1,15 +1,23 @@
 package controllers
 
 import (
+	"crypto"
+	"crypto/rsa"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
-	"os"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/Koshroy/turnover/activities"
+	"github.com/Koshroy/turnover/httpsig"
+	"github.com/Koshroy/turnover/keystore"
+	"github.com/Koshroy/turnover/ldcontext"
+	"github.com/Koshroy/turnover/seen"
+	"github.com/Koshroy/turnover/subscribers"
 	"github.com/Koshroy/turnover/tasks"
 	"github.com/gofrs/uuid"
 )
@@ -17,7 +25,7 @@ import (
 const followJSON = `{
     "@context": "https://www.w3.org/ns/activitystreams",
     "@type": "Follow",
-    "id": "https://activities.example.org/1",
+    "id": "https://sally.example.org/activities/1",
     "actor": "https://sally.example.org",
     "object": {
         "summary": "Follow request",
@@ -72,7 +80,7 @@ const missingIDFollowJSON = `{
 const noteJSON = `{
     "@context": "https://www.w3.org/ns/activitystreams",
     "@type": "Note",
-    "id": "https://activities.example.org/2",
+    "id": "https://sally.example.org/activities/2",
     "actor": "https://sally.example.org",
     "object": {
         "id": "https://notes.example.com/1"
@@ -83,53 +91,25 @@ const noteJSON = `{
 const createNoteJSON = `{
     "@context": "https://www.w3.org/ns/activitystreams",
     "@type": "Create",
-    "id": "https://activities.example.org/3",
+    "id": "https://sally.otherexample.org/activities/3",
     "actor": "https://sally.otherexample.org",
+    "to": "https://www.w3.org/ns/activitystreams#Public",
     "object": {
         "summary": "Note",
         "type": "Note",
         "id": "https://sally.otherexample.org/note/1",
-        "attributedTo": "https://john.otherexample.org"
+        "attributedTo": "https://sally.otherexample.org"
     }
 }
 `
 
-type mockTransport struct {
-	Fallback http.RoundTripper
-}
-
-// RoundTrip returns a response in the mock transport for a given request
-func (m *mockTransport) RoundTrip(req *http.Request) (*http.Response, error) {
-	if req.URL.Host != "www.w3.org" && req.URL.Path != "/ns/activitystreams" {
-		return m.Fallback.RoundTrip(req)
-	}
+// offlineTransport fails every request, making sure the inbox never
+// reaches out to the network in tests
+type offlineTransport struct{}
 
-	f, err := os.Open("./testdata/activitystreams.jsonld")
-	if err != nil {
-		return nil, fmt.Errorf("error opening testdata for mock transport: %v", err)
-	}
-
-	s, err := f.Stat()
-	if err != nil {
-		return nil, fmt.Errorf("error getting filesize for mock transport: %v", err)
-	}
-
-	header := make(http.Header)
-	header.Add("Content-Length", fmt.Sprintf("%d", s.Size()))
-	header.Add("Content-Type", "application/ld+json")
-	header.Add("Date", s.ModTime().Format(time.RFC1123))
-
-	return &http.Response{
-		Status:        http.StatusText(http.StatusOK),
-		StatusCode:    http.StatusOK,
-		Proto:         req.Proto,
-		ProtoMajor:    req.ProtoMajor,
-		ProtoMinor:    req.ProtoMinor,
-		ContentLength: s.Size(),
-		Request:       req,
-		Header:        header,
-		Body:          f,
-	}, nil
+// RoundTrip returns an error for every request
+func (offlineTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return nil, fmt.Errorf("unexpected network request to %s", req.URL)
 }
 
 type mockQueuer struct {
@@ -178,6 +158,10 @@ func (q *mockQueuer) ListFinished() []uuid.UUID {
 	return retTIDs
 }
 
+func (q *mockQueuer) Remove(taskID uuid.UUID) {
+	delete(q.finished, taskID)
+}
+
 func (q *mockQueuer) ListEnqueues() []uuid.UUID {
 	retTIDs := make([]uuid.UUID, 0)
 	for tID := range q.enqueued {
@@ -214,6 +198,10 @@ func (s *mockStorer) Put(task tasks.Task, taskID uuid.UUID) bool {
 	return s.storage.Put(task, taskID)
 }
 
+func (s *mockStorer) Delete(taskID uuid.UUID) {
+	s.storage.Delete(taskID)
+}
+
 func (s *mockStorer) Reset() {
 	s.storage = tasks.NewMemoryStorage()
 	s.getCalls = make(map[uuid.UUID]bool)
@@ -230,6 +218,21 @@ func (s *mockStorer) HasPutCall(taskID uuid.UUID) bool {
 	return ok
 }
 
+// subscriberInbox is the inbox of the subscriber in subscribedRegistry
+const subscriberInbox = "https://relay.example.net/inbox"
+
+// subscribedRegistry returns a registry with one subscriber, which relayed
+// activities are delivered to
+func subscribedRegistry() subscribers.Registry {
+	registry := subscribers.NewMemoryRegistry()
+	registry.Add(subscribers.Subscriber{
+		Actor: "https://relay.example.net/actor",
+		Inbox: subscriberInbox,
+		Since: time.Now(),
+	})
+	return registry
+}
+
 type respTest struct {
 	JSONInput   string
 	StatusCode  int
@@ -277,20 +280,523 @@ func testResp(t *testing.T, inbox *Inbox, queuer *mockQueuer, storer *mockStorer
 func TestInboxHandlerResponse(t *testing.T) {
 	t.Parallel()
 
-	mockClient := &http.Client{
-		Transport: &mockTransport{Fallback: http.DefaultTransport},
-	}
 	q := newMockQueuer()
 	s := newMockStorer()
-	i := NewInbox([]string{}, "https", "www.example.com", mockClient, q, s)
+	i := NewInbox([]string{}, "https", "www.example.com", ldcontext.NewLoader(), q, s, subscribedRegistry())
 
 	testResp(t, i, q, s, []respTest{
-		{followJSON, http.StatusOK, 0, "success_follow_json"},
-		{emptyIDFollowJSON, http.StatusOK, 0, "success_follow_json_empty_id"},
-		{nullIDFollowJSON, http.StatusUnsupportedMediaType, 0, "failure_follow_json_null_id"},
-		{missingIDFollowJSON, http.StatusUnsupportedMediaType, 0, "failure_follow_json_missing_id"},
+		{followJSON, http.StatusAccepted, 0, "success_follow_json"},
+		{emptyIDFollowJSON, http.StatusAccepted, 0, "success_follow_json_empty_id"},
+		{nullIDFollowJSON, http.StatusBadRequest, 0, "failure_follow_json_null_id"},
+		{missingIDFollowJSON, http.StatusBadRequest, 0, "failure_follow_json_missing_id"},
 		{noteJSON, http.StatusUnsupportedMediaType, 0, "failure_note_json"},
-		{createNoteJSON, http.StatusOK, 1, "success_create_note_json"},
+		{createNoteJSON, http.StatusAccepted, 1, "success_create_note_json"},
+	})
+
+}
+
+const wrongFollowJSON = `{
+    "@context": "https://www.w3.org/ns/activitystreams",
+    "type": "Follow",
+    "id": "https://sally.example.org/activities/1",
+    "actor": "https://sally.example.org",
+    "object": "https://www.example.com/followers"
+}
+`
+
+const foreignActivityJSON = `{
+    "@context": "https://www.w3.org/ns/activitystreams",
+    "type": "Announce",
+    "id": "https://john.example.org/activities/1",
+    "actor": "https://sally.example.org",
+    "object": "https://john.example.org/notes/1",
+    "to": "https://www.w3.org/ns/activitystreams#Public"
+}
+`
+
+const undoLikeJSON = `{
+    "@context": "https://www.w3.org/ns/activitystreams",
+    "type": "Undo",
+    "id": "https://sally.example.org/likes/1/undo",
+    "actor": "https://sally.example.org",
+    "object": {
+        "type": "Like",
+        "id": "https://sally.example.org/likes/1",
+        "actor": "https://sally.example.org",
+        "object": "https://www.example.com/inbox"
+    }
+}
+`
+
+func TestInboxProblemDetails(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"invalid json", `{"type": `, http.StatusBadRequest},
+		{"json array", `[]`, http.StatusBadRequest},
+		{"null id", nullIDFollowJSON, http.StatusBadRequest},
+		{"unsupported type", noteJSON, http.StatusUnsupportedMediaType},
+		{"follow of another resource", wrongFollowJSON, http.StatusBadRequest},
+		{"undo of another activity", undoLikeJSON, http.StatusBadRequest},
+		{"foreign activity", foreignActivityJSON, http.StatusForbidden},
+		{"too large", strings.Repeat(" ", int(DefaultLimits().BodySize)+1), http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		var tt = tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			i := NewInbox([]string{}, "https", "www.example.com", ldcontext.NewLoader(), newMockQueuer(), newMockStorer(), subscribedRegistry())
+
+			w := httptest.NewRecorder()
+			i.ServeHTTP(w, httptest.NewRequest("POST", "/", strings.NewReader(tt.body)))
+
+			if w.Code != tt.status {
+				t.Fatalf("expected %d got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+			if contentType := w.Header().Get("Content-Type"); contentType != problemJSONType {
+				t.Errorf("expected problem details got %s", contentType)
+			}
+
+			var details problem
+			err := json.Unmarshal(w.Body.Bytes(), &details)
+			if err != nil {
+				t.Fatalf("could not unmarshal problem details: %v", err)
+			}
+			if details.Status != tt.status || details.Title != http.StatusText(tt.status) || details.Detail == "" {
+				t.Errorf("unexpected problem details %+v", details)
+			}
+		})
+	}
+}
+
+func TestInboxFailedForwardResponds500(t *testing.T) {
+	t.Parallel()
+
+	i := NewInbox([]string{}, "https", "www.example.com", ldcontext.NewLoader(), failingQueuer{newMockQueuer()}, newMockStorer(), subscribedRegistry())
+
+	w := httptest.NewRecorder()
+	i.ServeHTTP(w, httptest.NewRequest("POST", "/", strings.NewReader(createNoteJSON)))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500 got %d", w.Code)
+	}
+}
+
+func TestInboxFollowRegistersSubscriber(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		name  string
+		actor map[string]interface{}
+		inbox string
+	}{
+		{"resolved inbox", map[string]interface{}{"id": "https://sally.example.org", "inbox": "https://sally.example.org/inbox"}, "https://sally.example.org/inbox"},
+		{"inbox on another origin", map[string]interface{}{"id": "https://sally.example.org", "inbox": "https://john.example.org/inbox"}, ""},
+		{"no inbox", map[string]interface{}{"id": "https://sally.example.org"}, ""},
+		{"unreachable actor", nil, ""},
+	}
+
+	for _, tt := range tests {
+		var tt = tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			docs := &staticDocuments{docs: map[string]map[string]interface{}{}}
+			if tt.actor != nil {
+				docs.docs["https://sally.example.org"] = tt.actor
+			}
+			registry := subscribers.NewMemoryRegistry()
+			i := NewInbox([]string{}, "https", "www.example.com", ldcontext.NewLoader(), newMockQueuer(), newMockStorer(), registry)
+			i.WithObjectFetcher(docs.fetch)
+
+			req := httptest.NewRequest("POST", "/", strings.NewReader(followJSON))
+			i.ServeHTTP(httptest.NewRecorder(), req)
+
+			sub, ok := registry.Get("https://sally.example.org")
+			if tt.inbox == "" {
+				if ok {
+					t.Errorf("expected a follower without a known inbox not to be registered, got %+v", sub)
+				}
+				return
+			}
+			if !ok || sub.Inbox != tt.inbox {
+				t.Errorf("expected follower to be registered with inbox %s, got %v", tt.inbox, registry.List())
+			}
+		})
+	}
+}
+
+func TestInboxForwardDoesNotWaitForWorkers(t *testing.T) {
+	t.Parallel()
+
+	registry := subscribedRegistry()
+	registry.Add(subscribers.Subscriber{
+		Actor: "https://relay.example.com/actor",
+		Inbox: "https://relay.example.com/inbox",
+		Since: time.Now(),
+	})
+
+	// no worker takes deliveries off the queue
+	queue := tasks.NewMemoryQueue()
+	i := NewInbox([]string{}, "https", "www.example.com", ldcontext.NewLoader(), queue, tasks.NewMemoryStorage(), registry)
+
+	w := httptest.NewRecorder()
+	i.ServeHTTP(w, httptest.NewRequest("POST", "/", strings.NewReader(createNoteJSON)))
+	if w.Code != http.StatusAccepted {
+		t.Errorf("expected 202 got %d", w.Code)
+	}
+}
+
+func TestInboxForwardPartialFailure(t *testing.T) {
+	t.Parallel()
+
+	registry := subscribedRegistry()
+	registry.Add(subscribers.Subscriber{
+		Actor: "https://relay.example.com/actor",
+		Inbox: "https://relay.example.com/inbox",
+		Since: time.Now(),
+	})
+
+	q := &flakyQueuer{mockQueuer: newMockQueuer(), failures: 1}
+	i := NewInbox([]string{}, "https", "www.example.com", ldcontext.NewLoader(), q, newMockStorer(), registry)
+
+	w := httptest.NewRecorder()
+	i.ServeHTTP(w, httptest.NewRequest("POST", "/", strings.NewReader(createNoteJSON)))
+	if w.Code != http.StatusAccepted {
+		t.Errorf("expected 202 when some deliveries were queued got %d", w.Code)
+	}
+	if len(q.enqueued) != 1 {
+		t.Errorf("expected the other subscriber to still be delivered to, got %d deliveries", len(q.enqueued))
+	}
+}
+
+// flakyQueuer refuses its first failures tasks
+type flakyQueuer struct {
+	*mockQueuer
+	failures int
+}
+
+func (q *flakyQueuer) Enqueue(taskID uuid.UUID) bool {
+	if q.failures > 0 {
+		q.failures--
+		return false
+	}
+	return q.mockQueuer.Enqueue(taskID)
+}
+
+func TestInboxFollowObjects(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		name   string
+		object string
+		status int
+	}{
+		{"relay actor", "https://www.example.com/actor", http.StatusAccepted},
+		{"public collection", "https://www.w3.org/ns/activitystreams#Public", http.StatusAccepted},
+		{"relay inbox", "https://www.example.com/inbox", http.StatusAccepted},
+		{"another actor", "https://www.example.com/users/someone", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		var tt = tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			docs := &staticDocuments{docs: map[string]map[string]interface{}{
+				"https://sally.example.org": {"id": "https://sally.example.org", "inbox": "https://sally.example.org/inbox"},
+			}}
+			registry := subscribers.NewMemoryRegistry()
+			i := NewInbox([]string{}, "https", "www.example.com", ldcontext.NewLoader(), newMockQueuer(), newMockStorer(), registry)
+			i.WithObjectFetcher(docs.fetch)
+
+			follow := `{
+    "@context": "https://www.w3.org/ns/activitystreams",
+    "type": "Follow",
+    "id": "https://sally.example.org/activities/1",
+    "actor": "https://sally.example.org",
+    "object": "` + tt.object + `"
+}`
+			w := httptest.NewRecorder()
+			i.ServeHTTP(w, httptest.NewRequest("POST", "/", strings.NewReader(follow)))
+			if w.Code != tt.status {
+				t.Fatalf("expected %d got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+
+			_, ok := registry.Get("https://sally.example.org")
+			if ok != (tt.status == http.StatusAccepted) {
+				t.Errorf("expected follower registered to be %v got %v", tt.status == http.StatusAccepted, ok)
+			}
+		})
+	}
+}
+
+// failingQueuer refuses every task
+type failingQueuer struct {
+	*mockQueuer
+}
+
+func (failingQueuer) Enqueue(taskID uuid.UUID) bool {
+	return false
+}
+
+func TestInboxDeduplicatesActivities(t *testing.T) {
+	t.Parallel()
+
+	q := newMockQueuer()
+	s := newMockStorer()
+	i := NewInbox([]string{}, "https", "www.example.com", ldcontext.NewLoader(), q, s, subscribedRegistry())
+	i.WithSeenSet(seen.NewMemorySet(time.Hour))
+
+	testResp(t, i, q, s, []respTest{
+		{createNoteJSON, http.StatusAccepted, 1, "first_delivery"},
+		{createNoteJSON, http.StatusAccepted, 0, "redelivery"},
+		{emptyIDFollowJSON, http.StatusAccepted, 0, "follow_without_id"},
+		{emptyIDFollowJSON, http.StatusAccepted, 0, "follow_without_id_again"},
 	})
+}
+
+func TestInboxForgetsFailedActivities(t *testing.T) {
+	t.Parallel()
+
+	set := seen.NewMemorySet(time.Hour)
+	i := NewInbox([]string{}, "https", "www.example.com", ldcontext.NewLoader(), failingQueuer{newMockQueuer()}, newMockStorer(), subscribedRegistry())
+	i.WithSeenSet(set)
+
+	req := httptest.NewRequest("POST", "/", strings.NewReader(createNoteJSON))
+	i.ServeHTTP(httptest.NewRecorder(), req)
+
+	if set.Len() != 0 {
+		t.Errorf("expected an activity which was not enqueued to be forgotten, %d ids remembered", set.Len())
+	}
+}
+
+// rsaKeys serves the public key of an RSA private key for every key ID
+type rsaKeys struct {
+	key *rsa.PrivateKey
+}
+
+func (k rsaKeys) PublicKey(keyID string) (crypto.PublicKey, error) {
+	return &k.key.PublicKey, nil
+}
+
+func TestInboxRelaysSignedToSubscribers(t *testing.T) {
+	t.Parallel()
+
+	store := keystore.MockStore()
+	verifier := httpsig.NewVerifier("http", rsaKeys{store.PrivKey()})
+
+	received := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		keyID, _, err := verifier.Verify(r, body)
+		if err != nil || r.Header.Get("Signature") == "" {
+			t.Errorf("expected a valid signature got %v", err)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		received <- keyID
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	registry := subscribers.NewMemoryRegistry()
+	registry.Add(subscribers.Subscriber{
+		Actor: "https://relay.example.net/actor",
+		Inbox: server.URL + "/inbox",
+		Since: time.Now(),
+	})
+
+	q := newMockQueuer()
+	s := newMockStorer()
+	signer := httpsig.NewSigner(store, func(id string) string {
+		return "https://www.example.com/actor#" + id
+	})
+	i := NewInbox([]string{}, "https", "www.example.com", ldcontext.NewLoader(), q, s, registry)
+	i.WithDeliverer(NewDeliverer(q, s, server.Client(), signer, httpsig.NewHostSchemes()))
+
+	w := httptest.NewRecorder()
+	i.ServeHTTP(w, httptest.NewRequest("POST", "/", strings.NewReader(announceJSON)))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected 202 got %d: %s", w.Code, w.Body.String())
+	}
+
+	enqueues := q.ListEnqueues()
+	if len(enqueues) != 1 {
+		t.Fatalf("expected one delivery got %d", len(enqueues))
+	}
+
+	task, ok := s.Get(enqueues[0])
+	if !ok {
+		t.Fatal("expected the delivery to be stored")
+	}
+	err := task.Run()
+	if err != nil {
+		t.Fatalf("could not deliver activity: %v", err)
+	}
+
+	select {
+	case keyID := <-received:
+		if !strings.HasPrefix(keyID, "https://www.example.com/actor#") {
+			t.Errorf("expected the relay key to sign the delivery got %s", keyID)
+		}
+	default:
+		t.Error("expected the subscriber inbox to receive the activity")
+	}
+}
+
+func TestInboxRequiresSignature(t *testing.T) {
+	t.Parallel()
+
+	mockClient := &http.Client{Transport: offlineTransport{}}
+	i := NewInbox([]string{}, "https", "www.example.com", ldcontext.NewLoader(), newMockQueuer(), newMockStorer(), subscribedRegistry())
+	i.WithVerifier(httpsig.NewVerifier("https", httpsig.NewKeyFetcher(httpsig.HTTPDocumentFetcher(mockClient), time.Hour)))
+
+	req := httptest.NewRequest("POST", "/", strings.NewReader(createNoteJSON))
+	w := httptest.NewRecorder()
+	i.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected unsigned request to be rejected with 401 got %d", w.Code)
+	}
+}
+
+const embeddedActorFollowJSON = `{
+    "@context": "https://www.w3.org/ns/activitystreams",
+    "type": "Follow",
+    "id": "https://sally.example.org/follows/1",
+    "actor": {
+        "id": "https://sally.example.org/actor",
+        "type": "Application",
+        "inbox": "https://sally.example.org/inbox"
+    },
+    "object": "https://www.example.com/inbox"
+}
+`
+
+func TestInboxFollowAnswers(t *testing.T) {
+	t.Parallel()
 
+	mockClient := &http.Client{Transport: offlineTransport{}}
+
+	var tests = []struct {
+		name       string
+		whitelist  []string
+		answer     string
+		subscribed bool
+	}{
+		{"open relay accepts", []string{}, "Accept", true},
+		{"whitelisted host accepts", []string{"sally.example.org"}, "Accept", true},
+		{"non-whitelisted host rejects", []string{"john.example.org"}, "Reject", false},
+	}
+
+	for _, tt := range tests {
+		var tt = tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			q := newMockQueuer()
+			s := newMockStorer()
+			registry := subscribers.NewMemoryRegistry()
+			outbox := activities.NewMemoryStore(0)
+			actor := NewActor("https", "www.example.com", keystore.MockStore(), Profile{})
+			publisher := NewPublisher(actor, outbox, NewDeliverer(q, s, mockClient, nil, nil))
+			i := NewInbox(tt.whitelist, "https", "www.example.com", ldcontext.NewLoader(), q, s, registry)
+			i.WithPublisher(publisher)
+
+			req := httptest.NewRequest("POST", "/", strings.NewReader(embeddedActorFollowJSON))
+			i.ServeHTTP(httptest.NewRecorder(), req)
+
+			sub, ok := registry.Get("https://sally.example.org/actor")
+			if ok != tt.subscribed {
+				t.Fatalf("expected subscribed to be %v got %v", tt.subscribed, ok)
+			}
+			if ok && sub.Inbox != "https://sally.example.org/inbox" {
+				t.Errorf("expected subscriber inbox to be recorded got %s", sub.Inbox)
+			}
+
+			if outbox.Count() != 0 {
+				t.Errorf("expected the %s not to be listed in the outbox", tt.answer)
+			}
+
+			enqueues := q.ListEnqueues()
+			if len(enqueues) != 1 {
+				t.Fatalf("expected %s to be delivered got %d deliveries", tt.answer, len(enqueues))
+			}
+
+			task, _ := s.Get(enqueues[0])
+			var answer map[string]interface{}
+			err := json.Unmarshal(task.(*tasks.Forward).Activity, &answer)
+			if err != nil || answer["type"] != tt.answer {
+				t.Errorf("expected %s to be delivered got %v (%v)", tt.answer, answer, err)
+			}
+		})
+	}
+}
+
+const embeddedActorUndoFollowJSON = `{
+    "@context": "https://www.w3.org/ns/activitystreams",
+    "type": "Undo",
+    "id": "https://sally.example.org/follows/1/undo",
+    "actor": {
+        "id": "https://sally.example.org/actor",
+        "type": "Application",
+        "inbox": "https://sally.example.org/inbox"
+    },
+    "object": {
+        "type": "Follow",
+        "id": "https://sally.example.org/follows/1",
+        "actor": "https://sally.example.org/actor",
+        "object": "https://www.example.com/inbox"
+    }
+}
+`
+
+func TestInboxUndoFollowRetractsAccept(t *testing.T) {
+	t.Parallel()
+
+	q := newMockQueuer()
+	s := newMockStorer()
+	registry := subscribers.NewMemoryRegistry()
+	outbox := activities.NewMemoryStore(0)
+	actor := NewActor("https", "www.example.com", keystore.MockStore(), Profile{})
+	publisher := NewPublisher(actor, outbox, NewDeliverer(q, s, &http.Client{Transport: offlineTransport{}}, nil, nil))
+	i := NewInbox([]string{}, "https", "www.example.com", ldcontext.NewLoader(), q, s, registry)
+	i.WithPublisher(publisher)
+
+	i.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/", strings.NewReader(embeddedActorFollowJSON)))
+	sub, ok := registry.Get("https://sally.example.org/actor")
+	if !ok || sub.Accept == uuid.Nil {
+		t.Fatalf("expected subscriber to record the accept got %+v", sub)
+	}
+
+	q.Reset()
+	i.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/", strings.NewReader(embeddedActorUndoFollowJSON)))
+	if _, ok := registry.Get("https://sally.example.org/actor"); ok {
+		t.Fatalf("expected subscriber to be removed")
+	}
+
+	code, doc := getActivity(t, NewActivities(actor, outbox), sub.Accept.String())
+	if code != http.StatusGone || doc["formerType"] != "Accept" {
+		t.Errorf("expected the accept to be served as a tombstone got %d %v", code, doc)
+	}
+
+	enqueues := q.ListEnqueues()
+	if len(enqueues) != 1 {
+		t.Fatalf("expected the delete to be delivered got %d deliveries", len(enqueues))
+	}
+	task, _ := s.Get(enqueues[0])
+	var deleted map[string]interface{}
+	err := json.Unmarshal(task.(*tasks.Forward).Activity, &deleted)
+	if err != nil || deleted["type"] != "Delete" || deleted["object"] != actor.ActivityID(sub.Accept) {
+		t.Errorf("expected a delete of the accept got %v (%v)", deleted, err)
+	}
+	if outbox.Count() != 0 {
+		t.Errorf("expected the delete of the accept not to be listed in the outbox")
+	}
 }
@@ -0,0 +1,69 @@
+package controllers
+
+const ldpInboxIRI = "http://www.w3.org/ns/ldp#inbox"
+
+const activityStreamsContext = "https://www.w3.org/ns/activitystreams"
+
+// activityJSONType is the media type of ActivityPub responses
+const activityJSONType = "application/activity+json"
+
+// nodeID returns the @id of the first node in an expanded JSON-LD value
+func nodeID(value interface{}) string {
+	node := firstNode(value)
+	if node == nil {
+		return ""
+	}
+
+	id, _ := node["@id"].(string)
+	return id
+}
+
+// nodeIDs returns the @id of every node in an expanded JSON-LD value
+func nodeIDs(value interface{}) []string {
+	var nodes []interface{}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		nodes = []interface{}{v}
+	case []interface{}:
+		nodes = v
+	}
+
+	ids := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		node, ok := n.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if id, ok := node["@id"].(string); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// nodeProperty returns the value of property on the first node in an
+// expanded JSON-LD value
+func nodeProperty(value interface{}, property string) interface{} {
+	node := firstNode(value)
+	if node == nil {
+		return nil
+	}
+
+	return node[property]
+}
+
+// firstNode returns the first node object in an expanded JSON-LD value
+func firstNode(value interface{}) map[string]interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return v
+	case []interface{}:
+		if len(v) == 0 {
+			return nil
+		}
+		node, _ := v[0].(map[string]interface{})
+		return node
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,208 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"time"
+
+	"github.com/piprate/json-gold/ld"
+)
+
+// ErrActivityTooLarge is returned when a request body exceeds the size limit
+var ErrActivityTooLarge = errors.New("activity is too large")
+
+// ErrActivityTooDeep is returned when a document nests JSON objects and
+// arrays deeper than the depth limit
+var ErrActivityTooDeep = errors.New("activity is nested too deeply")
+
+// ErrTooManyNodes is returned when a document holds more nodes than the
+// node limit
+var ErrTooManyNodes = errors.New("activity has too many nodes")
+
+// ErrExpansionTimeout is returned when JSON-LD expansion of a document takes
+// longer than the expansion time limit
+var ErrExpansionTimeout = errors.New("activity took too long to expand")
+
+// Limits bound the resources the Inbox spends on a single request. A zero
+// limit is not enforced
+type Limits struct {
+	// BodySize is the largest request body accepted, in bytes
+	BodySize int64
+	// Depth is how deeply JSON objects and arrays may be nested
+	Depth int
+	// Nodes is how many node objects a document may hold, before and
+	// after expansion
+	Nodes int
+	// ExpansionTime is how long JSON-LD expansion may take
+	ExpansionTime time.Duration
+	// Expansions is how many JSON-LD expansions may run at once, counting
+	// those which ran out of time and still finish in the background
+	Expansions int
+}
+
+// DefaultLimits returns the limits used when none are configured. They
+// leave plenty of room for real activities, which are a few kilobytes
+func DefaultLimits() Limits {
+	return Limits{
+		BodySize:      1 << 20, // 1 MB
+		Depth:         32,
+		Nodes:         1024,
+		ExpansionTime: 5 * time.Second,
+		Expansions:    runtime.NumCPU(),
+	}
+}
+
+// checkDepth scans a JSON document and fails when its objects and arrays
+// nest deeper than max, before the document is decoded
+func checkDepth(data []byte, max int) error {
+	if max <= 0 {
+		return nil
+	}
+
+	depth := 0
+	inString, escaped := false, false
+	for _, c := range data {
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+
+		switch c {
+		case '"':
+			inString = true
+		case '{', '[':
+			depth++
+			if depth > max {
+				return fmt.Errorf("%w: more than %d levels", ErrActivityTooDeep, max)
+			}
+		case '}', ']':
+			depth--
+		}
+	}
+	return nil
+}
+
+// checkNodes fails when value holds more than max JSON objects, not
+// counting the ones in contexts
+func checkNodes(value interface{}, max int) error {
+	if max <= 0 {
+		return nil
+	}
+
+	if countNodes(value, max) > max {
+		return fmt.Errorf("%w: more than %d nodes", ErrTooManyNodes, max)
+	}
+	return nil
+}
+
+// countNodes counts the JSON objects in value, stopping once it counted
+// more than max
+func countNodes(value interface{}, max int) int {
+	count := 0
+	switch v := value.(type) {
+	case map[string]interface{}:
+		count++
+		for key, entry := range v {
+			if count > max {
+				break
+			}
+			if key == "@context" {
+				continue
+			}
+			count += countNodes(entry, max-count)
+		}
+	case []interface{}:
+		for _, entry := range v {
+			if count > max {
+				break
+			}
+			count += countNodes(entry, max-count)
+		}
+	}
+	return count
+}
+
+// expand runs JSON-LD expansion on raw within the expansion time limit.
+// json-gold cannot be interrupted, so an expansion which runs out of time
+// is left to finish in the background, and loading further contexts fails
+// to cut it short. Expansions wait for one of the expansion slots, which
+// is only given back once json-gold returns, so documents which keep
+// running out of time cannot pile up goroutines
+func (i Inbox) expand(raw interface{}) ([]interface{}, error) {
+	if i.limits.ExpansionTime <= 0 {
+		if i.expansions != nil {
+			i.expansions <- struct{}{}
+			defer func() { <-i.expansions }()
+		}
+		return i.proc.Expand(raw, i.opts)
+	}
+
+	deadline := time.Now().Add(i.limits.ExpansionTime)
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+
+	if i.expansions != nil {
+		select {
+		case i.expansions <- struct{}{}:
+		case <-timer.C:
+			return nil, fmt.Errorf("%w: no expansion slot free within %v", ErrExpansionTimeout, i.limits.ExpansionTime)
+		}
+	}
+
+	opts := *i.opts
+	opts.DocumentLoader = deadlineLoader{loader: i.loader, deadline: deadline}
+
+	type result struct {
+		expanded []interface{}
+		err      error
+	}
+	done := make(chan result, 1)
+	go func() {
+		if i.expansions != nil {
+			defer func() { <-i.expansions }()
+		}
+		expanded, err := i.proc.Expand(raw, &opts)
+		done <- result{expanded, err}
+	}()
+
+	select {
+	case res := <-done:
+		if res.err != nil && time.Now().After(deadline) {
+			return nil, fmt.Errorf("%w: more than %v", ErrExpansionTimeout, i.limits.ExpansionTime)
+		}
+		return res.expanded, res.err
+	case <-timer.C:
+		return nil, fmt.Errorf("%w: more than %v", ErrExpansionTimeout, i.limits.ExpansionTime)
+	}
+}
+
+// newSemaphore returns a channel holding up to size slots, or nil when
+// size is not positive
+func newSemaphore(size int) chan struct{} {
+	if size <= 0 {
+		return nil
+	}
+	return make(chan struct{}, size)
+}
+
+// deadlineLoader fails to load documents once its deadline passed
+type deadlineLoader struct {
+	loader   ld.DocumentLoader
+	deadline time.Time
+}
+
+// LoadDocument loads u with the wrapped loader unless the deadline passed
+func (l deadlineLoader) LoadDocument(u string) (*ld.RemoteDocument, error) {
+	if time.Now().After(l.deadline) {
+		return nil, ld.NewJsonLdError(ld.LoadingDocumentFailed, ErrExpansionTimeout)
+	}
+	return l.loader.LoadDocument(u)
+}
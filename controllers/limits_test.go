@@ -0,0 +1,145 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Koshroy/turnover/ldcontext"
+	"github.com/piprate/json-gold/ld"
+)
+
+func TestCheckDepth(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		name    string
+		doc     string
+		max     int
+		wantErr bool
+	}{
+		{"flat object", `{"a": 1}`, 1, false},
+		{"nested object", `{"a": {"b": [1]}}`, 3, false},
+		{"too deep", `{"a": {"b": [1]}}`, 2, true},
+		{"brackets in strings", `{"a": "[[[{{{\"[["}`, 1, false},
+		{"no limit", strings.Repeat("[", 100) + strings.Repeat("]", 100), 0, false},
+	}
+
+	for _, tt := range tests {
+		err := checkDepth([]byte(tt.doc), tt.max)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: expected error %v got %v", tt.name, tt.wantErr, err)
+		}
+		if err != nil && !errors.Is(err, ErrActivityTooDeep) {
+			t.Errorf("%s: expected ErrActivityTooDeep got %v", tt.name, err)
+		}
+	}
+}
+
+func TestCheckNodes(t *testing.T) {
+	t.Parallel()
+
+	doc := map[string]interface{}{
+		"@context": map[string]interface{}{"a": map[string]interface{}{}},
+		"object": []interface{}{
+			map[string]interface{}{"id": "https://example.org/1"},
+			map[string]interface{}{"id": "https://example.org/2"},
+		},
+	}
+
+	if err := checkNodes(doc, 3); err != nil {
+		t.Errorf("expected three nodes to be within the limit got %v", err)
+	}
+	if err := checkNodes(doc, 2); !errors.Is(err, ErrTooManyNodes) {
+		t.Errorf("expected ErrTooManyNodes got %v", err)
+	}
+}
+
+// slowLoader takes delay to load every document
+type slowLoader struct {
+	loader ld.DocumentLoader
+	delay  time.Duration
+}
+
+func (l slowLoader) LoadDocument(u string) (*ld.RemoteDocument, error) {
+	time.Sleep(l.delay)
+	return l.loader.LoadDocument(u)
+}
+
+func TestInboxLimits(t *testing.T) {
+	t.Parallel()
+
+	expandedCreateJSON := strings.Replace(createNoteJSON,
+		`"@context": "https://www.w3.org/ns/activitystreams"`,
+		`"@context": ["https://www.w3.org/ns/activitystreams", "https://w3id.org/identity/v1"]`, 1)
+
+	var tests = []struct {
+		name   string
+		limits Limits
+		loader ld.DocumentLoader
+		body   string
+		status int
+	}{
+		{"within limits", DefaultLimits(), ldcontext.NewLoader(), createNoteJSON, http.StatusAccepted},
+		{"body size", Limits{BodySize: 16}, ldcontext.NewLoader(), createNoteJSON, http.StatusRequestEntityTooLarge},
+		{"depth", Limits{Depth: 1}, ldcontext.NewLoader(), createNoteJSON, http.StatusBadRequest},
+		{"nodes", Limits{Nodes: 1}, ldcontext.NewLoader(), createNoteJSON, http.StatusRequestEntityTooLarge},
+		{"expansion time", Limits{ExpansionTime: 20 * time.Millisecond}, slowLoader{ldcontext.NewLoader(), 50 * time.Millisecond}, expandedCreateJSON, http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		var tt = tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			i := NewInbox([]string{}, "https", "www.example.com", tt.loader, newMockQueuer(), newMockStorer(), subscribedRegistry())
+			i.WithLimits(tt.limits)
+
+			w := httptest.NewRecorder()
+			i.ServeHTTP(w, httptest.NewRequest("POST", "/", strings.NewReader(tt.body)))
+			if w.Code != tt.status {
+				t.Errorf("expected %d got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestInboxExpansionSlots(t *testing.T) {
+	t.Parallel()
+
+	expandedCreateJSON := strings.Replace(createNoteJSON,
+		`"@context": "https://www.w3.org/ns/activitystreams"`,
+		`"@context": ["https://www.w3.org/ns/activitystreams", "https://w3id.org/identity/v1"]`, 1)
+
+	i := NewInbox([]string{}, "https", "www.example.com", slowLoader{ldcontext.NewLoader(), 50 * time.Millisecond}, newMockQueuer(), newMockStorer(), subscribedRegistry())
+	i.WithLimits(Limits{ExpansionTime: 20 * time.Millisecond, Expansions: 1})
+
+	// the expansion runs out of time but keeps its slot until it finishes
+	w := httptest.NewRecorder()
+	i.ServeHTTP(w, httptest.NewRequest("POST", "/", strings.NewReader(expandedCreateJSON)))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 got %d: %s", w.Code, w.Body.String())
+	}
+	if len(i.expansions) != 1 {
+		t.Errorf("expected the timed out expansion to hold its slot")
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(i.expansions) != 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if len(i.expansions) != 0 {
+		t.Fatalf("expected the slot to be given back once the expansion finished")
+	}
+
+	// with every slot taken, expansions fail once their time runs out
+	i.expansions <- struct{}{}
+	w = httptest.NewRecorder()
+	i.ServeHTTP(w, httptest.NewRequest("POST", "/", strings.NewReader(expandedCreateJSON)))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 without a free slot got %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,23 @@
+package controllers
+
+import "expvar"
+
+// inboxMetrics are published through expvar under "inbox". activities
+// counts the activities accepted by the Inbox, and duplicates the ones it
+// had already seen
+var inboxMetrics = expvar.NewMap("inbox")
+
+func init() {
+	inboxMetrics.Set("duplicate_rate", expvar.Func(duplicateRate))
+}
+
+// duplicateRate returns the share of accepted activities which were
+// duplicates
+func duplicateRate() interface{} {
+	activities, _ := inboxMetrics.Get("activities").(*expvar.Int)
+	duplicates, _ := inboxMetrics.Get("duplicates").(*expvar.Int)
+	if activities == nil || duplicates == nil || activities.Value() == 0 {
+		return 0.0
+	}
+	return float64(duplicates.Value()) / float64(activities.Value())
+}
@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/Koshroy/turnover/subscribers"
+	"github.com/go-chi/chi"
+)
+
+const nodeInfoSchemaPrefix = "http://nodeinfo.diaspora.software/ns/schema/"
+
+var nodeInfoVersions = []string{"2.1", "2.0"}
+
+// NodeInfo is the controller logic for the NodeInfo discovery document
+// and the NodeInfo 2.0 and 2.1 documents
+type NodeInfo struct {
+	scheme, domain string
+	version        string
+	whitelist      []string
+	registry       subscribers.Registry
+}
+
+// NewNodeInfo creates a new NodeInfo controller. The relay is reported as
+// open for registrations when whitelist is empty
+func NewNodeInfo(
+	scheme, domain, version string,
+	whitelist []string,
+	registry subscribers.Registry,
+) NodeInfo {
+	return NodeInfo{
+		scheme:    scheme,
+		domain:    domain,
+		version:   version,
+		whitelist: whitelist,
+		registry:  registry,
+	}
+}
+
+// ServeDiscovery serves the /.well-known/nodeinfo discovery document
+func (n NodeInfo) ServeDiscovery(w http.ResponseWriter, r *http.Request) {
+	links := make([]map[string]string, 0, len(nodeInfoVersions))
+	for _, version := range nodeInfoVersions {
+		links = append(links, map[string]string{
+			"rel":  nodeInfoSchemaPrefix + version,
+			"href": n.scheme + "://" + n.domain + "/nodeinfo/" + version,
+		})
+	}
+
+	writeJSON(w, "application/json", map[string]interface{}{"links": links})
+}
+
+// ServeHTTP serves the NodeInfo document for the {version} route parameter
+func (n NodeInfo) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	version := chi.URLParam(r, "version")
+	if version != "2.0" && version != "2.1" {
+		http.Error(w, "unsupported NodeInfo version", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(
+		w,
+		`application/json; profile="`+nodeInfoSchemaPrefix+version+`#"`,
+		n.Document(version),
+	)
+}
+
+// Document returns the NodeInfo document for the given schema version
+func (n NodeInfo) Document(version string) map[string]interface{} {
+	software := map[string]interface{}{
+		"name":    "turnover",
+		"version": n.version,
+	}
+	if version == "2.1" {
+		software["repository"] = "https://github.com/Koshroy/turnover"
+		software["homepage"] = "https://github.com/Koshroy/turnover"
+	}
+
+	return map[string]interface{}{
+		"version":  version,
+		"software": software,
+		"protocols": []string{
+			"activitypub",
+		},
+		"services": map[string][]string{
+			"inbound":  {},
+			"outbound": {},
+		},
+		"openRegistrations": n.relayMode() == "open",
+		"usage": map[string]interface{}{
+			"users": map[string]int{
+				"total": 1,
+			},
+			"localPosts": 0,
+		},
+		"metadata": map[string]interface{}{
+			"subscribers": n.registry.Count(),
+			"relayMode":   n.relayMode(),
+		},
+	}
+}
+
+// relayMode reports whether anyone may subscribe to the relay or only
+// whitelisted instances
+func (n NodeInfo) relayMode() string {
+	if len(n.whitelist) > 0 {
+		return "whitelist"
+	}
+	return "open"
+}
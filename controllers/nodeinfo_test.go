@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Koshroy/turnover/subscribers"
+	"github.com/go-chi/chi"
+)
+
+func TestNodeInfoDiscovery(t *testing.T) {
+	t.Parallel()
+
+	n := NewNodeInfo("https", "www.example.com", "1.2.3", nil, subscribers.NewMemoryRegistry())
+	w := httptest.NewRecorder()
+	n.ServeDiscovery(w, httptest.NewRequest("GET", "/.well-known/nodeinfo", nil))
+
+	var discovery struct {
+		Links []map[string]string
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &discovery)
+	if err != nil {
+		t.Fatalf("could not unmarshal discovery document: %v", err)
+	}
+
+	if len(discovery.Links) != 2 ||
+		discovery.Links[0]["href"] != "https://www.example.com/nodeinfo/2.1" {
+		t.Errorf("expected links to NodeInfo 2.1 and 2.0 got %v", discovery.Links)
+	}
+}
+
+func TestNodeInfoDocument(t *testing.T) {
+	t.Parallel()
+
+	registry := subscribers.NewMemoryRegistry()
+	registry.Add(subscribers.Subscriber{Actor: "https://sally.example.org/actor"})
+	n := NewNodeInfo("https", "www.example.com", "1.2.3", []string{"example.org"}, registry)
+
+	var tests = []struct {
+		version string
+		want    int
+	}{
+		{"2.1", http.StatusOK},
+		{"2.0", http.StatusOK},
+		{"1.0", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		var tt = tt
+		t.Run("version_"+tt.version, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest("GET", "/nodeinfo/"+tt.version, nil)
+			routeCtx := chi.NewRouteContext()
+			routeCtx.URLParams.Add("version", tt.version)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, routeCtx))
+
+			w := httptest.NewRecorder()
+			n.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Fatalf("expected %d got %d", tt.want, w.Code)
+			}
+
+			if tt.want != http.StatusOK {
+				return
+			}
+
+			var doc struct {
+				Version  string
+				Software struct {
+					Name, Version string
+				}
+				Protocols         []string
+				OpenRegistrations bool
+				Metadata          struct {
+					Subscribers int
+					RelayMode   string
+				}
+			}
+			err := json.Unmarshal(w.Body.Bytes(), &doc)
+			if err != nil {
+				t.Fatalf("could not unmarshal NodeInfo document: %v", err)
+			}
+
+			if doc.Version != tt.version || doc.Software.Name != "turnover" ||
+				doc.Software.Version != "1.2.3" {
+				t.Errorf("unexpected software info %+v", doc)
+			}
+
+			if len(doc.Protocols) != 1 || doc.Protocols[0] != "activitypub" {
+				t.Errorf("expected activitypub protocol got %v", doc.Protocols)
+			}
+
+			if doc.OpenRegistrations || doc.Metadata.RelayMode != "whitelist" ||
+				doc.Metadata.Subscribers != 1 {
+				t.Errorf("unexpected relay metadata %+v", doc)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/Koshroy/turnover/models"
+)
+
+// ErrOriginMismatch is returned when an activity, its actor or its embedded
+// objects do not come from the origin of the key which signed it
+var ErrOriginMismatch = errors.New("activity does not come from the origin of its signer")
+
+// origin returns the scheme and host of iri, or an empty string when iri
+// is not an absolute IRI
+func origin(iri string) string {
+	u, err := url.Parse(iri)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return ""
+	}
+	return strings.ToLower(u.Scheme + "://" + u.Host)
+}
+
+// checkOrigins applies the same origin rules to activity. Its ID and actor
+// must share the origin of the signing key, and embedded objects must either
+// share it too or be confirmed by fetching them from their own origin.
+// Objects of Follow and Unfollow activities are references to the relay and
+// are not checked. keyOrigin is empty for unsigned requests, in which case
+// the origin of the actor is used
+func (i Inbox) checkOrigins(activity *models.Activity, keyOrigin string) error {
+	actors := nodeIDs(activity.Actor)
+	if len(actors) == 0 {
+		return fmt.Errorf("%w: activity has no actor", ErrOriginMismatch)
+	}
+
+	if keyOrigin == "" {
+		keyOrigin = origin(actors[0])
+	}
+
+	for _, actor := range actors {
+		if origin(actor) != keyOrigin {
+			return fmt.Errorf("%w: actor %s", ErrOriginMismatch, actor)
+		}
+	}
+
+	if activity.ID != nil && *activity.ID != "" && origin(*activity.ID) != keyOrigin {
+		return fmt.Errorf("%w: activity %s", ErrOriginMismatch, *activity.ID)
+	}
+
+	for _, activityType := range activity.Type {
+		if activityType == followIRI || activityType == unfollowIRI {
+			return nil
+		}
+	}
+
+	for idx := range activity.Object {
+		object := &activity.Object[idx]
+		if !embedded(object) || objectFromOrigin(object, keyOrigin) {
+			continue
+		}
+
+		err := i.confirmObject(object)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// embedded reports whether object is an embedded object rather than a
+// reference by IRI
+func embedded(object *models.Activity) bool {
+	return object.Type != nil || object.AttributedTo != nil || len(object.Object) > 0
+}
+
+// objectFromOrigin reports whether object and its authors share keyOrigin
+func objectFromOrigin(object *models.Activity, keyOrigin string) bool {
+	if object.ID == nil || origin(*object.ID) != keyOrigin {
+		return false
+	}
+
+	for _, author := range nodeIDs(object.AttributedTo) {
+		if origin(author) != keyOrigin {
+			return false
+		}
+	}
+	return true
+}
+
+// confirmObject fetches an embedded object from another origin and checks
+// its origin serves it as attributed to its own actors. Once confirmed the
+// embedded copy is replaced by a reference, so the unverified copy is not
+// relayed
+func (i Inbox) confirmObject(object *models.Activity) error {
+	if object.ID == nil || origin(*object.ID) == "" {
+		return fmt.Errorf("%w: embedded object without an id", ErrOriginMismatch)
+	}
+
+	objectID := *object.ID
+	if i.fetchObject == nil {
+		return fmt.Errorf("%w: object %s", ErrOriginMismatch, objectID)
+	}
+
+	doc, err := i.fetchObject(objectID)
+	if err != nil {
+		log.Printf("could not fetch object %s from its origin: %v\n", objectID, err)
+		return fmt.Errorf("%w: object %s could not be fetched", ErrOriginMismatch, objectID)
+	}
+
+	if fetchedID, _ := doc["id"].(string); fetchedID != objectID {
+		return fmt.Errorf("%w: object %s is served as %q", ErrOriginMismatch, objectID, fetchedID)
+	}
+
+	for _, author := range compactIDs(doc["attributedTo"]) {
+		if origin(author) != origin(objectID) {
+			return fmt.Errorf("%w: object %s is attributed to %s", ErrOriginMismatch, objectID, author)
+		}
+	}
+
+	*object = models.Activity{ID: &objectID}
+	return nil
+}
+
+// compactIDs returns the IRIs in a compacted JSON-LD value, which may be
+// an IRI, a node with an id or a list of either
+func compactIDs(value interface{}) []string {
+	values, ok := value.([]interface{})
+	if !ok {
+		values = []interface{}{value}
+	}
+
+	ids := make([]string, 0, len(values))
+	for _, v := range values {
+		switch entry := v.(type) {
+		case string:
+			ids = append(ids, entry)
+		case map[string]interface{}:
+			if id, ok := entry["id"].(string); ok {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
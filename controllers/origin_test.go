@@ -0,0 +1,144 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/Koshroy/turnover/ldcontext"
+	"github.com/Koshroy/turnover/models"
+)
+
+const foreignAnnounceJSON = `{
+    "@context": "https://www.w3.org/ns/activitystreams",
+    "type": "Announce",
+    "id": "https://sally.example.org/activities/announce/2",
+    "actor": "https://sally.example.org/users/sally",
+    "to": "https://www.w3.org/ns/activitystreams#Public",
+    "object": {
+        "id": "https://john.example.org/notes/1",
+        "type": "Note",
+        "attributedTo": "https://john.example.org/users/john"
+    }
+}
+`
+
+func TestCheckOrigins(t *testing.T) {
+	t.Parallel()
+
+	const sally = "https://sally.example.org"
+
+	var tests = []struct {
+		name      string
+		doc       string
+		keyOrigin string
+		wantErr   bool
+	}{
+		{"same origin", createNoteJSON, "https://sally.otherexample.org", false},
+		{"unsigned uses actor origin", createNoteJSON, "", false},
+		{"key from another origin", createNoteJSON, sally, true},
+		{"activity id from another origin", addressedCreateWithID("https://john.example.org/activities/1"), sally, true},
+		{"object from another origin", foreignAnnounceJSON, sally, true},
+		{"referenced object from another origin", announceJSON, sally, false},
+		{"follow of the relay", followJSON, sally, false},
+	}
+
+	for _, tt := range tests {
+		var tt = tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			i := NewInbox(nil, "https", "www.example.com", ldcontext.NewLoader(), nil, nil, subscribedRegistry())
+			activity := mustParse(t, i, tt.doc)
+
+			err := i.checkOrigins(activity, tt.keyOrigin)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v got %v", tt.wantErr, err)
+			}
+			if err != nil && !errors.Is(err, ErrOriginMismatch) {
+				t.Errorf("expected ErrOriginMismatch got %v", err)
+			}
+		})
+	}
+}
+
+func TestCheckOriginsFetchesForeignObjects(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		name    string
+		served  map[string]interface{}
+		fetchOK bool
+		wantErr bool
+	}{
+		{
+			"confirmed by origin",
+			map[string]interface{}{"id": "https://john.example.org/notes/1", "attributedTo": "https://john.example.org/users/john"},
+			true, false,
+		},
+		{
+			"served under another id",
+			map[string]interface{}{"id": "https://john.example.org/notes/2", "attributedTo": "https://john.example.org/users/john"},
+			true, true,
+		},
+		{
+			"attributed to another origin",
+			map[string]interface{}{"id": "https://john.example.org/notes/1", "attributedTo": []interface{}{map[string]interface{}{"id": "https://sally.example.org/users/sally"}}},
+			true, true,
+		},
+		{"fetch fails", nil, false, true},
+	}
+
+	for _, tt := range tests {
+		var tt = tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			i := NewInbox(nil, "https", "www.example.com", ldcontext.NewLoader(), nil, nil, subscribedRegistry())
+			i.WithObjectFetcher(func(iri string) (map[string]interface{}, error) {
+				if !tt.fetchOK {
+					return nil, fmt.Errorf("could not fetch %s", iri)
+				}
+				return tt.served, nil
+			})
+
+			activity := mustParse(t, i, foreignAnnounceJSON)
+			err := i.checkOrigins(activity, "https://sally.example.org")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v got %v", tt.wantErr, err)
+			}
+
+			if err == nil && embedded(&activity.Object[0]) {
+				t.Error("expected the confirmed object to be replaced by a reference")
+			}
+		})
+	}
+}
+
+func addressedCreateWithID(id string) string {
+	return fmt.Sprintf(`{
+    "@context": "https://www.w3.org/ns/activitystreams",
+    "type": "Create",
+    "id": %q,
+    "actor": "https://sally.example.org/users/sally",
+    "object": "https://sally.example.org/notes/1"
+}
+`, id)
+}
+
+func mustParse(t *testing.T, i *Inbox, doc string) *models.Activity {
+	t.Helper()
+
+	var raw interface{}
+	err := json.Unmarshal([]byte(doc), &raw)
+	if err != nil {
+		t.Fatalf("could not unmarshal document: %v", err)
+	}
+
+	activity, err := i.parseActivity(raw)
+	if err != nil {
+		t.Fatalf("could not parse activity: %v", err)
+	}
+	return activity
+}
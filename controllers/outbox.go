@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/Koshroy/turnover/activities"
+)
+
+// Outbox is the controller logic for the /outbox endpoint, which lists the
+// activities published by the relay as a paged OrderedCollection
+type Outbox struct {
+	scheme, domain string
+	store          activities.Store
+	pageSize       int
+}
+
+// NewOutbox creates a new Outbox
+func NewOutbox(scheme, domain string, store activities.Store, pageSize int) Outbox {
+	if pageSize <= 0 {
+		pageSize = defaultCollectionPageSize
+	}
+
+	return Outbox{
+		scheme:   scheme,
+		domain:   domain,
+		store:    store,
+		pageSize: pageSize,
+	}
+}
+
+func (o Outbox) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	pageParam := r.URL.Query().Get("page")
+	if pageParam == "" {
+		writeJSON(w, activityJSONType, map[string]interface{}{
+			"@context":   activityStreamsContext,
+			"id":         o.pageURL(0),
+			"type":       "OrderedCollection",
+			"totalItems": o.store.Count(),
+			"first":      o.pageURL(1),
+		})
+		return
+	}
+
+	page, err := strconv.Atoi(pageParam)
+	if err != nil || page < 1 {
+		http.Error(w, "invalid page", http.StatusBadRequest)
+		return
+	}
+
+	total := o.store.Count()
+	if page > lastPage(total, o.pageSize) {
+		http.Error(w, "page not found", http.StatusNotFound)
+		return
+	}
+
+	offset := (page - 1) * o.pageSize
+	items := make([]interface{}, 0, o.pageSize)
+	for _, activity := range o.store.List(offset, o.pageSize) {
+		item, err := activityDocument(activity, activityIRI(o.scheme, o.domain, activity.ID))
+		if err != nil {
+			log.Printf("%v\n", err)
+			continue
+		}
+		delete(item, "@context")
+		items = append(items, item)
+	}
+
+	doc := map[string]interface{}{
+		"@context":     activityStreamsContext,
+		"id":           o.pageURL(page),
+		"type":         "OrderedCollectionPage",
+		"partOf":       o.pageURL(0),
+		"totalItems":   total,
+		"orderedItems": items,
+	}
+	if offset+o.pageSize < total {
+		doc["next"] = o.pageURL(page + 1)
+	}
+	if page > 1 {
+		doc["prev"] = o.pageURL(page - 1)
+	}
+
+	writeJSON(w, activityJSONType, doc)
+}
+
+// pageURL returns the URL of the given page, or of the outbox itself when
+// page is 0
+func (o Outbox) pageURL(page int) string {
+	u := url.URL{
+		Scheme: o.scheme,
+		Host:   o.domain,
+		Path:   "/outbox",
+	}
+
+	if page > 0 {
+		u.RawQuery = url.Values{"page": []string{strconv.Itoa(page)}}.Encode()
+	}
+	return u.String()
+}
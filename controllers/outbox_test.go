@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Koshroy/turnover/activities"
+	"github.com/Koshroy/turnover/keystore"
+)
+
+func TestOutboxHandler(t *testing.T) {
+	t.Parallel()
+
+	outbox := activities.NewMemoryStore(0)
+	actor := NewActor("https", "www.example.com", keystore.MockStore(), Profile{})
+	publisher := NewPublisher(actor, outbox, NewDeliverer(newMockQueuer(), newMockStorer(), http.DefaultClient, nil, nil))
+	for _, activityType := range []string{"Accept", "Update", "Reject"} {
+		_, err := publisher.Publish(activityType, "https://sally.example.org/follows/1", []string{publicIRI}, nil, nil)
+		if err != nil {
+			t.Fatalf("could not publish %s: %v", activityType, err)
+		}
+	}
+
+	// answers to follows are addressed to the follower and not listed, so
+	// the outbox does not reveal who follows the relay
+	accept, err := publisher.Publish("Accept", "https://john.example.org/follows/1", []string{"https://john.example.org/actor"}, nil, nil)
+	if err != nil {
+		t.Fatalf("could not publish Accept: %v", err)
+	}
+
+	o := NewOutbox("https", "www.example.com", outbox, 2)
+
+	w := httptest.NewRecorder()
+	o.ServeHTTP(w, httptest.NewRequest("GET", "/outbox", nil))
+	var doc map[string]interface{}
+	err = json.Unmarshal(w.Body.Bytes(), &doc)
+	if err != nil {
+		t.Fatalf("could not unmarshal outbox: %v", err)
+	}
+	testStrings(t, doc, []stringTest{
+		{"id", "https://www.example.com/outbox"},
+		{"type", "OrderedCollection"},
+		{"first", "https://www.example.com/outbox?page=1"},
+	})
+	if doc["totalItems"] != float64(3) {
+		t.Errorf("expected 3 total items got %v", doc["totalItems"])
+	}
+
+	w = httptest.NewRecorder()
+	o.ServeHTTP(w, httptest.NewRequest("GET", "/outbox?page=1", nil))
+	var page struct {
+		Next         string
+		OrderedItems []map[string]interface{}
+	}
+	err = json.Unmarshal(w.Body.Bytes(), &page)
+	if err != nil {
+		t.Fatalf("could not unmarshal outbox page: %v", err)
+	}
+
+	if len(page.OrderedItems) != 2 || page.OrderedItems[0]["type"] != "Reject" {
+		t.Errorf("expected newest two activities first got %v", page.OrderedItems)
+	}
+
+	if page.OrderedItems[0]["actor"] != "https://www.example.com/actor" {
+		t.Errorf("expected relay actor on outbox activities got %v", page.OrderedItems[0]["actor"])
+	}
+
+	if page.Next != "https://www.example.com/outbox?page=2" {
+		t.Errorf("expected next page link got %s", page.Next)
+	}
+
+	for _, item := range page.OrderedItems {
+		if item["id"] == accept["id"] {
+			t.Errorf("expected the Accept of a follow not to be listed")
+		}
+	}
+
+	for _, pageParam := range []string{"3", "922337203685477581"} {
+		w = httptest.NewRecorder()
+		o.ServeHTTP(w, httptest.NewRequest("GET", "/outbox?page="+pageParam, nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected page %s past the end to return 404 got %d", pageParam, w.Code)
+		}
+	}
+}
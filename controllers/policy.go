@@ -0,0 +1,239 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/Koshroy/turnover/models"
+	"github.com/Koshroy/turnover/subscribers"
+)
+
+const announceIRI = "https://www.w3.org/ns/activitystreams#Announce"
+const likeIRI = "https://www.w3.org/ns/activitystreams#Like"
+const addIRI = "https://www.w3.org/ns/activitystreams#Add"
+const removeIRI = "https://www.w3.org/ns/activitystreams#Remove"
+const moveIRI = "https://www.w3.org/ns/activitystreams#Move"
+const flagIRI = "https://www.w3.org/ns/activitystreams#Flag"
+
+// Policy is how the Inbox handles an activity type
+type Policy string
+
+const (
+	// PolicyRelay forwards activities to subscribers
+	PolicyRelay Policy = "relay"
+	// PolicyIgnore accepts activities and drops them
+	PolicyIgnore Policy = "ignore"
+	// PolicyLocal processes activities on the relay itself
+	PolicyLocal Policy = "local"
+)
+
+// activityTypes maps the IRIs of the supported activity types to their names
+var activityTypes = map[string]string{
+	followIRI:   "Follow",
+	unfollowIRI: "Unfollow",
+	undoIRI:     "Undo",
+	createIRI:   "Create",
+	readIRI:     "Read",
+	updateIRI:   "Update",
+	deleteIRI:   "Delete",
+	announceIRI: "Announce",
+	likeIRI:     "Like",
+	addIRI:      "Add",
+	removeIRI:   "Remove",
+	moveIRI:     "Move",
+	flagIRI:     "Flag",
+}
+
+// localTypes are the activity types the relay can process itself
+var localTypes = map[string]bool{
+	"Follow":   true,
+	"Unfollow": true,
+	"Undo":     true,
+	"Move":     true,
+	"Flag":     true,
+}
+
+// subscriptionTypes are always processed locally, since they manage
+// subscriptions to the relay
+var subscriptionTypes = map[string]bool{
+	"Follow":   true,
+	"Unfollow": true,
+	"Undo":     true,
+}
+
+// Policies maps activity type names, such as Announce, to how the Inbox
+// handles them
+type Policies map[string]Policy
+
+// DefaultPolicies returns the policies used when none are configured.
+// Content is relayed, reactions and collection changes are ignored, and
+// subscriptions, moves and reports are processed locally
+func DefaultPolicies() Policies {
+	return Policies{
+		"Follow":   PolicyLocal,
+		"Unfollow": PolicyLocal,
+		"Undo":     PolicyLocal,
+		"Create":   PolicyRelay,
+		"Read":     PolicyRelay,
+		"Update":   PolicyRelay,
+		"Delete":   PolicyRelay,
+		"Announce": PolicyRelay,
+		"Like":     PolicyIgnore,
+		"Add":      PolicyIgnore,
+		"Remove":   PolicyIgnore,
+		"Move":     PolicyLocal,
+		"Flag":     PolicyLocal,
+	}
+}
+
+// ParsePolicies returns the default policies with overrides applied.
+// overrides maps activity type names to relay, ignore or local
+func ParsePolicies(overrides map[string]string) (Policies, error) {
+	policies := DefaultPolicies()
+	for typeName, value := range overrides {
+		if _, ok := policies[typeName]; !ok {
+			return nil, fmt.Errorf("unsupported activity type %q", typeName)
+		}
+
+		policy := Policy(value)
+		switch policy {
+		case PolicyRelay, PolicyIgnore:
+		case PolicyLocal:
+			if !localTypes[typeName] {
+				return nil, fmt.Errorf("%s activities cannot be processed locally", typeName)
+			}
+		default:
+			return nil, fmt.Errorf("unknown policy %q for %s activities", value, typeName)
+		}
+
+		if subscriptionTypes[typeName] && policy != PolicyLocal {
+			return nil, fmt.Errorf("%s activities are always processed locally", typeName)
+		}
+		policies[typeName] = policy
+	}
+
+	return policies, nil
+}
+
+// For returns the policy for activity. Activities with several types must
+// have the same policy for all of them
+func (p Policies) For(activity *models.Activity) (Policy, error) {
+	var policy Policy
+	for _, activityType := range activity.Type {
+		typePolicy, ok := p[activityTypes[activityType]]
+		if !ok {
+			return "", ErrUnsupportedActivityType
+		}
+
+		if policy != "" && policy != typePolicy {
+			return "", ErrUnsupportedActivityType
+		}
+		policy = typePolicy
+	}
+
+	if policy == "" {
+		return "", ErrUnsupportedActivityType
+	}
+	return policy, nil
+}
+
+// processLocally handles an activity with the local policy
+func (i Inbox) processLocally(activity *models.Activity) {
+	for _, activityType := range activity.Type {
+		switch activityType {
+		case followIRI, unfollowIRI, undoIRI:
+			i.updateSubscription(activity)
+			return
+		case moveIRI:
+			i.moveSubscriber(activity)
+			return
+		case flagIRI:
+			logReport(activity)
+			return
+		}
+	}
+}
+
+// moveSubscriber moves the subscription of an actor which announced it
+// moved to its target. The target must list the actor in its alsoKnownAs,
+// so an actor cannot take over a subscription it does not own. The
+// subscriber inbox is only kept when the target lives on the same host
+func (i Inbox) moveSubscriber(activity *models.Activity) {
+	actorID := nodeID(activity.Actor)
+	targetID := nodeID(activity.Target)
+	if actorID == "" || targetID == "" {
+		log.Println("move activity has no actor or target, ignoring")
+		return
+	}
+
+	if len(activity.Object) == 0 || activity.Object[0].ID == nil || *activity.Object[0].ID != actorID {
+		log.Printf("move from %s does not move its own actor, ignoring\n", actorID)
+		return
+	}
+
+	sub, ok := i.registry.Get(actorID)
+	if !ok {
+		return
+	}
+
+	if !i.whitelisted(targetID) {
+		log.Printf("subscriber %s moved to non-whitelisted actor %s, unsubscribing\n", actorID, targetID)
+		i.registry.Remove(actorID)
+		return
+	}
+
+	target, err := i.fetchActor(targetID)
+	if err != nil {
+		log.Printf("could not resolve move target %s: %v\n", targetID, err)
+		return
+	}
+	if !knownAs(target, actorID) {
+		log.Printf("move target %s does not list %s in alsoKnownAs, ignoring\n", targetID, actorID)
+		return
+	}
+
+	inbox := ""
+	if sameHost(actorID, targetID) {
+		inbox = sub.Inbox
+	} else {
+		inbox = actorInbox(targetID, target)
+	}
+
+	i.registry.Remove(actorID)
+	i.registry.Add(subscribers.Subscriber{
+		Actor:  targetID,
+		Inbox:  inbox,
+		Since:  sub.Since,
+		Accept: sub.Accept,
+	})
+	log.Printf("moved subscription of %s to %s\n", actorID, targetID)
+}
+
+// logReport records a Flag activity for the operator to review
+func logReport(activity *models.Activity) {
+	objects := make([]string, 0, len(activity.Object))
+	for _, object := range activity.Object {
+		if object.ID != nil {
+			objects = append(objects, *object.ID)
+		}
+	}
+
+	log.Printf("report %s from %s about %s\n", *activity.ID, nodeID(activity.Actor), strings.Join(objects, ", "))
+}
+
+// sameHost reports whether two IRIs are on the same host
+func sameHost(a, b string) bool {
+	aURL, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+
+	bURL, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+
+	return aURL.Host != "" && strings.EqualFold(aURL.Host, bURL.Host)
+}
@@ -0,0 +1,195 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Koshroy/turnover/ldcontext"
+	"github.com/Koshroy/turnover/models"
+	"github.com/Koshroy/turnover/subscribers"
+)
+
+const likeJSON = `{
+    "@context": "https://www.w3.org/ns/activitystreams",
+    "type": "Like",
+    "id": "https://sally.example.org/likes/1",
+    "actor": "https://sally.example.org",
+    "object": "https://john.example.org/notes/1",
+    "to": "https://www.w3.org/ns/activitystreams#Public"
+}
+`
+
+const flagJSON = `{
+    "@context": "https://www.w3.org/ns/activitystreams",
+    "type": "Flag",
+    "id": "https://sally.example.org/flags/1",
+    "actor": "https://sally.example.org",
+    "object": ["https://john.example.org/users/john", "https://john.example.org/notes/1"]
+}
+`
+
+const moveJSON = `{
+    "@context": "https://www.w3.org/ns/activitystreams",
+    "type": "Move",
+    "id": "https://sally.example.org/moves/1",
+    "actor": "https://sally.example.org/actor",
+    "object": "https://sally.example.org/actor",
+    "target": "https://sally.example.org/new-actor"
+}
+`
+
+func TestParsePolicies(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		name      string
+		overrides map[string]string
+		wantErr   bool
+	}{
+		{"no overrides", nil, false},
+		{"ignore announces", map[string]string{"Announce": "ignore"}, false},
+		{"relay likes", map[string]string{"Like": "relay"}, false},
+		{"relay flags", map[string]string{"Flag": "relay"}, false},
+		{"unknown type", map[string]string{"Block": "relay"}, true},
+		{"unknown policy", map[string]string{"Create": "forward"}, true},
+		{"local create", map[string]string{"Create": "local"}, true},
+		{"ignore follows", map[string]string{"Follow": "ignore"}, true},
+	}
+
+	for _, tt := range tests {
+		policies, err := ParsePolicies(tt.overrides)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: expected error %v got %v", tt.name, tt.wantErr, err)
+			continue
+		}
+
+		for typeName, policy := range tt.overrides {
+			if err == nil && policies[typeName] != Policy(policy) {
+				t.Errorf("%s: expected %s policy %s got %s", tt.name, typeName, policy, policies[typeName])
+			}
+		}
+	}
+}
+
+func TestPoliciesFor(t *testing.T) {
+	t.Parallel()
+
+	policies := DefaultPolicies()
+
+	var tests = []struct {
+		name    string
+		types   []string
+		want    Policy
+		wantErr bool
+	}{
+		{"announce", []string{announceIRI}, PolicyRelay, false},
+		{"like", []string{likeIRI}, PolicyIgnore, false},
+		{"flag", []string{flagIRI}, PolicyLocal, false},
+		{"matching types", []string{createIRI, updateIRI}, PolicyRelay, false},
+		{"mixed policies", []string{createIRI, likeIRI}, "", true},
+		{"unknown type", []string{"https://www.w3.org/ns/activitystreams#Note"}, "", true},
+		{"no type", nil, "", true},
+	}
+
+	for _, tt := range tests {
+		policy, err := policies.For(&models.Activity{Type: tt.types})
+		if (err != nil) != tt.wantErr || policy != tt.want {
+			t.Errorf("%s: expected %q (error %v) got %q (%v)", tt.name, tt.want, tt.wantErr, policy, err)
+		}
+	}
+}
+
+func TestInboxPolicies(t *testing.T) {
+	t.Parallel()
+
+	q := newMockQueuer()
+	s := newMockStorer()
+	i := NewInbox([]string{}, "https", "www.example.com", ldcontext.NewLoader(), q, s, subscribedRegistry())
+
+	testResp(t, i, q, s, []respTest{
+		{announceJSON, http.StatusAccepted, 1, "relay_announce"},
+		{likeJSON, http.StatusAccepted, 0, "ignore_like"},
+		{flagJSON, http.StatusAccepted, 0, "local_flag"},
+	})
+
+	policies, err := ParsePolicies(map[string]string{"Announce": "ignore", "Like": "relay"})
+	if err != nil {
+		t.Fatalf("could not parse policies: %v", err)
+	}
+	i.WithPolicies(policies)
+
+	testResp(t, i, q, s, []respTest{
+		{announceJSON, http.StatusAccepted, 0, "ignore_announce"},
+		{likeJSON, http.StatusAccepted, 1, "relay_like"},
+	})
+}
+
+func TestInboxMoveSubscriber(t *testing.T) {
+	t.Parallel()
+
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var tests = []struct {
+		name   string
+		target map[string]interface{}
+		moved  bool
+	}{
+		{"confirmed", map[string]interface{}{"id": "https://sally.example.org/new-actor", "alsoKnownAs": []interface{}{"https://sally.example.org/actor"}}, true},
+		{"confirmed by a single alias", map[string]interface{}{"id": "https://sally.example.org/new-actor", "alsoKnownAs": "https://sally.example.org/actor"}, true},
+		{"not an alias", map[string]interface{}{"id": "https://sally.example.org/new-actor", "alsoKnownAs": []interface{}{"https://john.example.org/actor"}}, false},
+		{"no aliases", map[string]interface{}{"id": "https://sally.example.org/new-actor"}, false},
+		{"served with another id", map[string]interface{}{"id": "https://john.example.org/actor", "alsoKnownAs": "https://sally.example.org/actor"}, false},
+		{"unreachable", nil, false},
+	}
+
+	for _, tt := range tests {
+		var tt = tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			registry := subscribers.NewMemoryRegistry()
+			registry.Add(subscribers.Subscriber{
+				Actor: "https://sally.example.org/actor",
+				Inbox: "https://sally.example.org/inbox",
+				Since: since,
+			})
+
+			docs := &staticDocuments{docs: map[string]map[string]interface{}{}}
+			if tt.target != nil {
+				docs.docs["https://sally.example.org/new-actor"] = tt.target
+			}
+			i := NewInbox([]string{}, "https", "www.example.com", ldcontext.NewLoader(), newMockQueuer(), newMockStorer(), registry)
+			i.WithObjectFetcher(docs.fetch)
+
+			w := httptest.NewRecorder()
+			i.ServeHTTP(w, httptest.NewRequest("POST", "/", strings.NewReader(moveJSON)))
+			if w.Code != http.StatusAccepted {
+				t.Fatalf("expected 202 got %d", w.Code)
+			}
+
+			if !tt.moved {
+				if _, ok := registry.Get("https://sally.example.org/actor"); !ok {
+					t.Error("expected the old actor to stay subscribed")
+				}
+				if _, ok := registry.Get("https://sally.example.org/new-actor"); ok {
+					t.Error("expected the new actor not to be subscribed")
+				}
+				return
+			}
+
+			if _, ok := registry.Get("https://sally.example.org/actor"); ok {
+				t.Error("expected the old actor to be unsubscribed")
+			}
+
+			sub, ok := registry.Get("https://sally.example.org/new-actor")
+			if !ok {
+				t.Fatalf("expected the new actor to be subscribed, got %v", registry.List())
+			}
+			if sub.Inbox != "https://sally.example.org/inbox" || !sub.Since.Equal(since) {
+				t.Errorf("expected inbox and subscription time to carry over, got %+v", sub)
+			}
+		})
+	}
+}
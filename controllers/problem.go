@@ -0,0 +1,41 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+// problemJSONType is the media type of problem details
+const problemJSONType = "application/problem+json"
+
+// problem is an RFC 7807 problem details document
+type problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// writeProblem responds with status and a problem details body explaining
+// detail
+func writeProblem(w http.ResponseWriter, status int, detail string) {
+	body, err := json.Marshal(problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	})
+	if err != nil {
+		log.Printf("error marshalling problem: %v\n", err)
+		w.WriteHeader(status)
+		return
+	}
+
+	w.Header().Set("Content-Type", problemJSONType)
+	w.WriteHeader(status)
+	_, err = w.Write(body)
+	if err != nil {
+		log.Printf("error writing response: %v\n", err)
+	}
+}
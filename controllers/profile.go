@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"mime"
+	"net/url"
+	"path"
+)
+
+const (
+	defaultProfileName     = "turnover relay"
+	defaultProfileSummary  = "An ActivityPub Relay"
+	defaultProfileUsername = "relay"
+)
+
+// profileContext defines the terms used by profile fields which are not
+// part of the ActivityStreams context
+var profileContext = map[string]string{
+	"manuallyApprovesFollowers": "as:manuallyApprovesFollowers",
+	"toot":                      "http://joinmastodon.org/ns#",
+	"discoverable":              "toot:discoverable",
+	"schema":                    "http://schema.org#",
+	"PropertyValue":             "schema:PropertyValue",
+	"value":                     "schema:value",
+}
+
+// Profile holds the operator facing fields of the relay actor. Empty
+// names, summaries and usernames fall back to the defaults
+type Profile struct {
+	Name string
+	// Summary is HTML
+	Summary           string
+	PreferredUsername string
+	// Icon and Image are URLs of the avatar and header images
+	Icon, Image               string
+	ManuallyApprovesFollowers bool
+	Discoverable              bool
+	Attachments               []ProfileField
+}
+
+// ProfileField is a name and value pair published as a PropertyValue,
+// such as a contact address or a link to the rules. Value is HTML
+type ProfileField struct {
+	Name, Value string
+}
+
+// DisplayName returns the name of the relay actor
+func (p Profile) DisplayName() string {
+	if p.Name == "" {
+		return defaultProfileName
+	}
+	return p.Name
+}
+
+// SummaryHTML returns the summary of the relay actor
+func (p Profile) SummaryHTML() string {
+	if p.Summary == "" {
+		return defaultProfileSummary
+	}
+	return p.Summary
+}
+
+// Username returns the preferredUsername of the relay actor
+func (p Profile) Username() string {
+	if p.PreferredUsername == "" {
+		return defaultProfileUsername
+	}
+	return p.PreferredUsername
+}
+
+// addTo sets the profile fields on an actor document
+func (p Profile) addTo(doc map[string]interface{}) {
+	doc["name"] = p.DisplayName()
+	doc["summary"] = p.SummaryHTML()
+	doc["preferredUsername"] = p.Username()
+	doc["manuallyApprovesFollowers"] = p.ManuallyApprovesFollowers
+	doc["discoverable"] = p.Discoverable
+
+	if p.Icon != "" {
+		doc["icon"] = imageObject(p.Icon)
+	}
+	if p.Image != "" {
+		doc["image"] = imageObject(p.Image)
+	}
+
+	if len(p.Attachments) > 0 {
+		attachments := make([]map[string]string, 0, len(p.Attachments))
+		for _, field := range p.Attachments {
+			attachments = append(attachments, map[string]string{
+				"type":  "PropertyValue",
+				"name":  field.Name,
+				"value": field.Value,
+			})
+		}
+		doc["attachment"] = attachments
+	}
+}
+
+// imageObject returns an Image object for imageURL, with a media type
+// guessed from its extension when possible
+func imageObject(imageURL string) map[string]string {
+	image := map[string]string{
+		"type": "Image",
+		"url":  imageURL,
+	}
+
+	u, err := url.Parse(imageURL)
+	if err == nil {
+		mediaType := mime.TypeByExtension(path.Ext(u.Path))
+		if mediaType != "" {
+			image["mediaType"] = mediaType
+		}
+	}
+
+	return image
+}
@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/Koshroy/turnover/activities"
+	"github.com/gofrs/uuid"
+)
+
+// Publisher publishes activities authored by the relay actor. Published
+// activities are recorded in the outbox and delivered to remote inboxes
+type Publisher struct {
+	actor     Actor
+	outbox    activities.Store
+	deliverer *Deliverer
+}
+
+// NewPublisher creates a new Publisher
+func NewPublisher(actor Actor, outbox activities.Store, deliverer *Deliverer) *Publisher {
+	return &Publisher{
+		actor:     actor,
+		outbox:    outbox,
+		deliverer: deliverer,
+	}
+}
+
+// Publish creates an activity of activityType with the given object and
+// addressing, stores it in the outbox and delivers it to inboxes. Inboxes
+// which cannot be delivered to are logged and skipped. Activities which are
+// not addressed to the public, such as answers to follows, are not listed
+// in the outbox
+func (p *Publisher) Publish(
+	activityType string,
+	object interface{},
+	to, cc []string,
+	inboxes []string,
+) (map[string]interface{}, error) {
+	_, activity, err := p.publish(activityType, object, to, cc, inboxes)
+	return activity, err
+}
+
+// publish is Publish which also returns the ID of the activity in the
+// outbox
+func (p *Publisher) publish(
+	activityType string,
+	object interface{},
+	to, cc []string,
+	inboxes []string,
+) (uuid.UUID, map[string]interface{}, error) {
+	id, err := uuid.NewV4()
+	if err != nil {
+		return uuid.Nil, nil, fmt.Errorf("error generating activity ID: %v", err)
+	}
+
+	published := time.Now().UTC()
+	activity := map[string]interface{}{
+		"@context":  activityStreamsContext,
+		"id":        p.actor.ActivityID(id),
+		"type":      activityType,
+		"actor":     p.actor.ID(),
+		"object":    object,
+		"published": published.Format(time.RFC3339),
+	}
+	if len(to) > 0 {
+		activity["to"] = to
+	}
+	if len(cc) > 0 {
+		activity["cc"] = cc
+	}
+
+	activityBytes, err := json.Marshal(activity)
+	if err != nil {
+		return uuid.Nil, nil, fmt.Errorf("error marshalling %s: %v", activityType, err)
+	}
+
+	stored := p.outbox.Put(activities.Activity{
+		ID:        id,
+		Type:      activityType,
+		Published: published,
+		Data:      activityBytes,
+		Unlisted:  !containsString(to, publicIRI) && !containsString(cc, publicIRI),
+	})
+	if !stored {
+		return uuid.Nil, nil, fmt.Errorf("could not store %s in outbox", activityType)
+	}
+
+	for _, inbox := range inboxes {
+		target, err := url.Parse(inbox)
+		if err != nil || !target.IsAbs() {
+			log.Printf("invalid inbox %s, skipping %s\n", inbox, activityType)
+			continue
+		}
+
+		err = p.deliverer.Deliver(activityBytes, *target)
+		if err != nil {
+			return uuid.Nil, nil, err
+		}
+	}
+
+	return id, activity, nil
+}
+
+// Retract deletes a published activity, so it is served as a Tombstone, and
+// publishes a Delete of it addressed to to and delivered to inboxes
+func (p *Publisher) Retract(id uuid.UUID, to []string, inboxes []string) error {
+	if !p.outbox.Delete(id, time.Now().UTC()) {
+		return fmt.Errorf("unknown activity %s", id)
+	}
+
+	_, err := p.Publish("Delete", p.actor.ActivityID(id), to, nil, inboxes)
+	return err
+}
+
+// containsString reports whether values holds value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
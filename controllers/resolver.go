@@ -0,0 +1,271 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Koshroy/turnover/httpsig"
+	"github.com/Koshroy/turnover/models"
+)
+
+const tombstoneIRI = "https://www.w3.org/ns/activitystreams#Tombstone"
+
+// resolvedTypes are the activity types whose referenced objects are
+// resolved before they are relayed, so the checks see the actual objects
+var resolvedTypes = map[string]bool{
+	announceIRI: true,
+	updateIRI:   true,
+	deleteIRI:   true,
+}
+
+type cachedObject struct {
+	doc     map[string]interface{}
+	fetched time.Time
+}
+
+// ObjectResolver dereferences object IRIs and caches the documents. Its
+// Resolve method is a httpsig.DocumentFetcher, so the Inbox can use it with
+// WithObjectFetcher. fetch should refuse non-public addresses, as a client
+// from httpsig.NewPublicClient does, since the IRIs come from remote servers
+type ObjectResolver struct {
+	fetch httpsig.DocumentFetcher
+	ttl   time.Duration
+	size  int
+
+	mu    sync.Mutex
+	cache map[string]cachedObject
+}
+
+// NewObjectResolver creates a new ObjectResolver which fetches documents
+// with fetch and caches up to size of them for ttl
+func NewObjectResolver(fetch httpsig.DocumentFetcher, ttl time.Duration, size int) *ObjectResolver {
+	return &ObjectResolver{
+		fetch: fetch,
+		ttl:   ttl,
+		size:  size,
+		cache: make(map[string]cachedObject),
+	}
+}
+
+// Resolve returns the document at iri, which callers must not modify.
+// Failed fetches are not cached
+func (r *ObjectResolver) Resolve(iri string) (map[string]interface{}, error) {
+	r.mu.Lock()
+	cached, ok := r.cache[iri]
+	r.mu.Unlock()
+	if ok && time.Since(cached.fetched) < r.ttl {
+		return cached.doc, nil
+	}
+
+	doc, err := r.fetch(iri)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.cache) >= r.size {
+		r.evict()
+	}
+	r.cache[iri] = cachedObject{doc: doc, fetched: time.Now()}
+	return doc, nil
+}
+
+// evict drops expired documents, or the oldest one when none expired
+func (r *ObjectResolver) evict() {
+	oldest := ""
+	for iri, cached := range r.cache {
+		if time.Since(cached.fetched) >= r.ttl {
+			delete(r.cache, iri)
+			continue
+		}
+		if oldest == "" || cached.fetched.Before(r.cache[oldest].fetched) {
+			oldest = iri
+		}
+	}
+
+	if len(r.cache) >= r.size && oldest != "" {
+		delete(r.cache, oldest)
+	}
+}
+
+// resolveObjects returns a copy of activity where the objects it only
+// references are replaced by the documents they resolve to, for checking
+// what an Announce, Update or Delete is about. The activity itself is
+// relayed unchanged. Objects which cannot be resolved stay references
+func (i Inbox) resolveObjects(activity *models.Activity) *models.Activity {
+	if i.fetchObject == nil || !hasResolvedType(activity) {
+		return activity
+	}
+
+	resolved := *activity
+	resolved.Object = make([]models.Activity, len(activity.Object))
+	for idx, object := range activity.Object {
+		resolved.Object[idx] = object
+		if embedded(&object) || object.ID == nil || origin(*object.ID) == "" {
+			continue
+		}
+
+		node, err := i.resolveObject(*object.ID)
+		if err != nil {
+			log.Printf("could not resolve object %s: %v\n", *object.ID, err)
+			continue
+		}
+		resolved.Object[idx] = *node
+	}
+	return &resolved
+}
+
+// resolveObject fetches the object at iri and builds a node from it
+func (i Inbox) resolveObject(iri string) (*models.Activity, error) {
+	doc, err := i.fetchObject(iri)
+	if err != nil {
+		return nil, err
+	}
+
+	node, ok := compactActivity(doc)
+	if !ok {
+		expanded, err := i.expand(doc)
+		if err != nil {
+			return nil, err
+		}
+		if len(expanded) != 1 {
+			return nil, fmt.Errorf("%w: %s holds %d nodes", ErrAmbiguousDocument, iri, len(expanded))
+		}
+
+		raw, ok := expanded[0].(map[string]interface{})
+		if !ok {
+			return nil, ErrMalformedActivity
+		}
+		node, err = hydrateNode(raw)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if node.ID == nil || *node.ID != iri {
+		return nil, fmt.Errorf("%w: %s is served with another id", ErrOriginMismatch, iri)
+	}
+	return node, nil
+}
+
+// hasResolvedType reports whether activity has a type whose objects are
+// resolved
+func hasResolvedType(activity *models.Activity) bool {
+	for _, activityType := range activity.Type {
+		if resolvedTypes[activityType] {
+			return true
+		}
+	}
+	return false
+}
+
+// deletesLiveObject reports whether activity is a Delete of an object its
+// origin still serves as something other than a Tombstone, which means
+// the Delete did not come from the object's author. resolved is activity
+// with its objects resolved
+func deletesLiveObject(activity, resolved *models.Activity) bool {
+	isDelete := false
+	for _, activityType := range activity.Type {
+		if activityType == deleteIRI {
+			isDelete = true
+		}
+	}
+	if !isDelete || resolved == activity {
+		return false
+	}
+
+	for idx := range activity.Object {
+		object := &resolved.Object[idx]
+		if embedded(&activity.Object[idx]) || len(object.Type) == 0 {
+			continue
+		}
+
+		tombstone := false
+		for _, objectType := range object.Type {
+			if objectType == tombstoneIRI {
+				tombstone = true
+			}
+		}
+		if !tombstone {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveInbox returns the inbox of the actor at actorID, or an empty
+// string when it cannot be resolved
+func (i Inbox) resolveInbox(actorID string) string {
+	if i.fetchObject == nil {
+		return ""
+	}
+
+	doc, err := i.fetchActor(actorID)
+	if err != nil {
+		log.Printf("could not resolve actor %s: %v\n", actorID, err)
+		return ""
+	}
+	return actorInbox(actorID, doc)
+}
+
+// fetchActor fetches the actor at actorID, which must be served with that
+// id
+func (i Inbox) fetchActor(actorID string) (map[string]interface{}, error) {
+	if i.fetchObject == nil {
+		return nil, errors.New("no object fetcher configured")
+	}
+
+	doc, err := i.fetchObject(actorID)
+	if err != nil {
+		return nil, err
+	}
+
+	if id, _ := doc["id"].(string); id != actorID {
+		return nil, fmt.Errorf("%w: %s is served with another id", ErrOriginMismatch, actorID)
+	}
+	return doc, nil
+}
+
+// actorInbox returns the inbox of the actor document doc at actorID, or an
+// empty string when it has none on the actor's origin
+func actorInbox(actorID string, doc map[string]interface{}) string {
+	inbox, _ := doc["inbox"].(string)
+	if inbox == "" {
+		return ""
+	}
+	if origin(inbox) != origin(actorID) {
+		log.Printf("actor %s has an inbox on another origin, ignoring it\n", actorID)
+		return ""
+	}
+	return inbox
+}
+
+// knownAs reports whether the actor document doc lists alias in its
+// alsoKnownAs
+func knownAs(doc map[string]interface{}, alias string) bool {
+	var aliases []interface{}
+	switch v := doc["alsoKnownAs"].(type) {
+	case []interface{}:
+		aliases = v
+	default:
+		aliases = []interface{}{v}
+	}
+
+	for _, a := range aliases {
+		switch v := a.(type) {
+		case string:
+			if v == alias {
+				return true
+			}
+		case map[string]interface{}:
+			if id, _ := v["id"].(string); id == alias {
+				return true
+			}
+		}
+	}
+	return false
+}
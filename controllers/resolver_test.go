@@ -0,0 +1,154 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Koshroy/turnover/ldcontext"
+	"github.com/Koshroy/turnover/subscribers"
+)
+
+// staticDocuments serves documents by IRI and counts the fetches
+type staticDocuments struct {
+	docs    map[string]map[string]interface{}
+	fetches int
+}
+
+func (s *staticDocuments) fetch(iri string) (map[string]interface{}, error) {
+	s.fetches++
+	doc, ok := s.docs[iri]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return doc, nil
+}
+
+func TestObjectResolverCaches(t *testing.T) {
+	t.Parallel()
+
+	docs := &staticDocuments{docs: map[string]map[string]interface{}{
+		"https://john.example.org/notes/1": {"id": "https://john.example.org/notes/1"},
+		"https://john.example.org/notes/2": {"id": "https://john.example.org/notes/2"},
+	}}
+	resolver := NewObjectResolver(docs.fetch, time.Hour, 1)
+
+	for n := 0; n < 2; n++ {
+		_, err := resolver.Resolve("https://john.example.org/notes/1")
+		if err != nil {
+			t.Fatalf("could not resolve note: %v", err)
+		}
+	}
+	if docs.fetches != 1 {
+		t.Errorf("expected the note to be fetched once got %d fetches", docs.fetches)
+	}
+
+	_, _ = resolver.Resolve("https://john.example.org/notes/2")
+	_, _ = resolver.Resolve("https://john.example.org/notes/1")
+	if docs.fetches != 3 {
+		t.Errorf("expected the first note to be evicted got %d fetches", docs.fetches)
+	}
+
+	_, err := resolver.Resolve("https://john.example.org/notes/3")
+	if err == nil {
+		t.Error("expected a missing object to fail")
+	}
+}
+
+const deleteJSON = `{
+    "@context": "https://www.w3.org/ns/activitystreams",
+    "id": "https://john.example.org/activities/delete/1",
+    "type": "Delete",
+    "actor": "https://john.example.org/users/john",
+    "object": "https://john.example.org/notes/1",
+    "to": "https://www.w3.org/ns/activitystreams#Public"
+}
+`
+
+func note(addressedTo string) map[string]interface{} {
+	return map[string]interface{}{
+		"@context":     "https://www.w3.org/ns/activitystreams",
+		"id":           "https://john.example.org/notes/1",
+		"type":         "Note",
+		"attributedTo": "https://john.example.org/users/john",
+		"to":           addressedTo,
+	}
+}
+
+func TestInboxResolvesObjects(t *testing.T) {
+	t.Parallel()
+
+	tombstone := map[string]interface{}{
+		"@context": "https://www.w3.org/ns/activitystreams",
+		"id":       "https://john.example.org/notes/1",
+		"type":     "Tombstone",
+	}
+
+	var tests = []struct {
+		name        string
+		doc         string
+		object      map[string]interface{}
+		numEnqueues int
+	}{
+		{"announce of public note", announceJSON, note("https://www.w3.org/ns/activitystreams#Public"), 1},
+		{"announce of followers only note", announceJSON, note("https://john.example.org/users/john/followers"), 0},
+		{"announce of unresolvable note", announceJSON, nil, 1},
+		{"delete of tombstone", deleteJSON, tombstone, 1},
+		{"delete of gone note", deleteJSON, nil, 1},
+		{"delete of live note", deleteJSON, note("https://www.w3.org/ns/activitystreams#Public"), 0},
+	}
+
+	for _, tt := range tests {
+		var tt = tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			docs := &staticDocuments{docs: map[string]map[string]interface{}{}}
+			if tt.object != nil {
+				docs.docs["https://john.example.org/notes/1"] = tt.object
+			}
+
+			q := newMockQueuer()
+			i := NewInbox([]string{}, "https", "www.example.com", ldcontext.NewLoader(), q, newMockStorer(), subscribedRegistry())
+			i.WithObjectFetcher(docs.fetch)
+
+			w := httptest.NewRecorder()
+			i.ServeHTTP(w, httptest.NewRequest("POST", "/", strings.NewReader(tt.doc)))
+			if w.Code != http.StatusAccepted {
+				t.Fatalf("expected 202 got %d: %s", w.Code, w.Body.String())
+			}
+			if len(q.ListEnqueues()) != tt.numEnqueues {
+				t.Errorf("expected %d enqueues got %d", tt.numEnqueues, len(q.ListEnqueues()))
+			}
+		})
+	}
+}
+
+func TestInboxResolvesSubscriberInbox(t *testing.T) {
+	t.Parallel()
+
+	docs := &staticDocuments{docs: map[string]map[string]interface{}{
+		"https://sally.example.org": {
+			"id":    "https://sally.example.org",
+			"type":  "Person",
+			"inbox": "https://sally.example.org/inbox",
+		},
+	}}
+
+	registry := subscribers.NewMemoryRegistry()
+	i := NewInbox([]string{}, "https", "www.example.com", ldcontext.NewLoader(), newMockQueuer(), newMockStorer(), registry)
+	i.WithObjectFetcher(docs.fetch)
+
+	i.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/", strings.NewReader(followJSON)))
+
+	sub, ok := registry.Get("https://sally.example.org")
+	if !ok {
+		t.Fatal("expected follower to be registered")
+	}
+	if sub.Inbox != "https://sally.example.org/inbox" {
+		t.Errorf("expected the resolved inbox to be recorded got %q", sub.Inbox)
+	}
+}
@@ -0,0 +1,29 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+// writeJSON writes v as the JSON response body with the given content type
+func writeJSON(w http.ResponseWriter, contentType string, v interface{}) {
+	writeJSONStatus(w, http.StatusOK, contentType, v)
+}
+
+// writeJSONStatus writes v as the JSON response body with the given status
+// and content type
+func writeJSONStatus(w http.ResponseWriter, status int, contentType string, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	_, err = w.Write(b)
+	if err != nil {
+		log.Printf("error writing response: %v\n", err)
+	}
+}
@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"crypto/rsa"
+	"log"
+	"time"
+
+	"github.com/Koshroy/turnover/subscribers"
+)
+
+const publicIRI = "https://www.w3.org/ns/activitystreams#Public"
+
+// KeyRotator rotates the signing key of the relay actor and tells
+// subscribers about key changes with an Update of the actor
+type KeyRotator struct {
+	actor     Actor
+	registry  subscribers.Registry
+	publisher *Publisher
+	grace     time.Duration
+}
+
+// NewKeyRotator creates a new KeyRotator. Rotated out keys stay advertised
+// on the actor for grace before they are retired
+func NewKeyRotator(
+	actor Actor,
+	registry subscribers.Registry,
+	publisher *Publisher,
+	grace time.Duration,
+) *KeyRotator {
+	return &KeyRotator{
+		actor:     actor,
+		registry:  registry,
+		publisher: publisher,
+		grace:     grace,
+	}
+}
+
+// Rotate makes privKey the active signing key and announces it
+func (k *KeyRotator) Rotate(privKey *rsa.PrivateKey) error {
+	key, err := k.actor.Store.Rotate(privKey)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("rotated signing key to %s\n", k.actor.KeyID(key.ID))
+	return k.BroadcastUpdate()
+}
+
+// Reload reloads the private key from disk and announces it if it changed
+func (k *KeyRotator) Reload() error {
+	rotated, err := k.actor.Store.Reload()
+	if err != nil {
+		return err
+	}
+
+	if !rotated {
+		return nil
+	}
+
+	log.Printf("rotated signing key to %s\n", k.actor.KeyID(k.actor.Store.ActiveKey().ID))
+	return k.BroadcastUpdate()
+}
+
+// RetireExpired stops advertising keys whose grace period has passed
+func (k *KeyRotator) RetireExpired(now time.Time) error {
+	retired := k.actor.Store.RetireExpired(k.grace, now)
+	if len(retired) == 0 {
+		return nil
+	}
+
+	for _, key := range retired {
+		log.Printf("retired signing key %s\n", k.actor.KeyID(key.ID))
+	}
+	return k.BroadcastUpdate()
+}
+
+// BroadcastUpdate sends an Update of the relay actor to every subscriber
+func (k *KeyRotator) BroadcastUpdate() error {
+	inboxes := make([]string, 0)
+	for _, sub := range k.registry.List() {
+		if sub.Inbox == "" {
+			log.Printf("no known inbox for subscriber %s, skipping update\n", sub.Actor)
+			continue
+		}
+		inboxes = append(inboxes, sub.Inbox)
+	}
+
+	_, err := k.publisher.Publish(
+		"Update",
+		k.actor.Document(),
+		[]string{publicIRI},
+		[]string{k.actor.routeURL("/followers", "").String()},
+		inboxes,
+	)
+	return err
+}
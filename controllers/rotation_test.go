@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Koshroy/turnover/activities"
+	"github.com/Koshroy/turnover/keystore"
+	"github.com/Koshroy/turnover/subscribers"
+)
+
+func TestKeyRotatorRotate(t *testing.T) {
+	t.Parallel()
+
+	store := keystore.MockStore()
+	actor := NewActor("https", "www.example.com", store, Profile{})
+	registry := subscribers.NewMemoryRegistry()
+	registry.Add(subscribers.Subscriber{
+		Actor: "https://sally.example.org/actor",
+		Inbox: "https://sally.example.org/inbox",
+	})
+	registry.Add(subscribers.Subscriber{Actor: "https://john.example.org/actor"})
+	q := newMockQueuer()
+	s := newMockStorer()
+	deliverer := NewDeliverer(q, s, http.DefaultClient, nil, nil)
+	outbox := activities.NewMemoryStore(0)
+	rotator := NewKeyRotator(actor, registry, NewPublisher(actor, outbox, deliverer), time.Hour)
+
+	privKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("could not generate RSA key: %v", err)
+	}
+
+	previousID := store.ActiveKey().ID
+	err = rotator.Rotate(privKey)
+	if err != nil {
+		t.Fatalf("could not rotate key: %v", err)
+	}
+
+	if len(q.ListEnqueues()) != 1 {
+		t.Errorf("expected 1 update to be enqueued got %d", len(q.ListEnqueues()))
+	}
+
+	if outbox.Count() != 1 || outbox.List(0, 1)[0].Type != "Update" {
+		t.Errorf("expected update to be recorded in the outbox")
+	}
+
+	pubKeys, ok := actor.Document()["publicKey"].([]map[string]string)
+	if !ok || len(pubKeys) != 2 {
+		t.Fatalf("expected 2 advertised keys got %v", actor.Document()["publicKey"])
+	}
+
+	if pubKeys[1]["id"] != actor.KeyID(previousID) {
+		t.Errorf("expected previous key to still be advertised got %s", pubKeys[1]["id"])
+	}
+
+	if pubKeys[0]["id"] != actor.KeyID(store.ActiveKey().ID) {
+		t.Errorf("expected new key to be advertised first got %s", pubKeys[0]["id"])
+	}
+
+	q.Reset()
+	err = rotator.RetireExpired(time.Now())
+	if err != nil {
+		t.Fatalf("could not retire keys: %v", err)
+	}
+
+	if len(store.Keys()) != 2 || len(q.ListEnqueues()) != 0 {
+		t.Errorf("expected no keys to retire within the grace period")
+	}
+
+	err = rotator.RetireExpired(time.Now().Add(2 * time.Hour))
+	if err != nil {
+		t.Fatalf("could not retire keys: %v", err)
+	}
+
+	if len(store.Keys()) != 1 || len(q.ListEnqueues()) != 1 {
+		t.Errorf("expected previous key to retire after the grace period")
+	}
+}
@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+)
+
+// WebFinger is the controller logic for the /.well-known/webfinger endpoint
+type WebFinger struct {
+	actor Actor
+}
+
+// NewWebFinger creates a new WebFinger for the relay actor
+func NewWebFinger(actor Actor) WebFinger {
+	return WebFinger{actor: actor}
+}
+
+// Subject returns the acct: URI of the relay actor
+func (wf WebFinger) Subject() string {
+	return "acct:" + wf.actor.PreferredUsername() + "@" + wf.actor.Domain
+}
+
+func (wf WebFinger) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	resource := r.URL.Query().Get("resource")
+	if resource == "" {
+		http.Error(w, "resource parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	if !wf.matches(resource) {
+		http.Error(w, "unknown resource", http.StatusNotFound)
+		return
+	}
+
+	jrd := map[string]interface{}{
+		"subject": wf.Subject(),
+		"aliases": []string{wf.actor.ID()},
+		"links": []map[string]string{
+			{
+				"rel":  "self",
+				"type": "application/activity+json",
+				"href": wf.actor.ID(),
+			},
+		},
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	writeJSON(w, "application/jrd+json", jrd)
+}
+
+// matches reports whether resource names the relay actor, either by its
+// acct: URI or by its actor IRI. Hosts are compared case insensitively
+func (wf WebFinger) matches(resource string) bool {
+	if resource == wf.actor.ID() {
+		return true
+	}
+
+	acct := strings.TrimPrefix(resource, "acct:")
+	at := strings.LastIndex(acct, "@")
+	if at < 0 {
+		return false
+	}
+
+	return acct[:at] == wf.actor.PreferredUsername() &&
+		strings.EqualFold(acct[at+1:], wf.actor.Domain)
+}
@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/Koshroy/turnover/keystore"
+)
+
+func TestWebFingerHandler(t *testing.T) {
+	t.Parallel()
+
+	wf := NewWebFinger(NewActor("https", "www.example.com", keystore.MockStore(), Profile{}))
+
+	var tests = []struct {
+		name     string
+		resource string
+		want     int
+	}{
+		{"acct resource", "acct:relay@www.example.com", http.StatusOK},
+		{"acct resource with different host case", "acct:relay@WWW.example.com", http.StatusOK},
+		{"actor IRI resource", "https://www.example.com/actor", http.StatusOK},
+		{"unknown user", "acct:sally@www.example.com", http.StatusNotFound},
+		{"unknown host", "acct:relay@example.org", http.StatusNotFound},
+		{"missing resource", "", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		var tt = tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest("GET", "/.well-known/webfinger?resource="+url.QueryEscape(tt.resource), nil)
+			w := httptest.NewRecorder()
+			wf.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Fatalf("expected %d got %d", tt.want, w.Code)
+			}
+
+			if tt.want != http.StatusOK {
+				return
+			}
+
+			if w.Header().Get("Content-Type") != "application/jrd+json" {
+				t.Errorf("expected application/jrd+json got %s", w.Header().Get("Content-Type"))
+			}
+
+			var jrd struct {
+				Subject string
+				Links   []map[string]string
+			}
+			err := json.Unmarshal(w.Body.Bytes(), &jrd)
+			if err != nil {
+				t.Fatalf("could not unmarshal JRD: %v", err)
+			}
+
+			if jrd.Subject != "acct:relay@www.example.com" {
+				t.Errorf("expected subject acct:relay@www.example.com got %s", jrd.Subject)
+			}
+
+			if len(jrd.Links) != 1 || jrd.Links[0]["href"] != "https://www.example.com/actor" {
+				t.Errorf("expected self link to the actor got %v", jrd.Links)
+			}
+		})
+	}
+}
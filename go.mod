@@ -1,5 +1,7 @@
 module github.com/Koshroy/turnover
 
+go 1.21
+
 require (
 	github.com/BurntSushi/toml v0.3.1
 	github.com/go-chi/chi v3.3.3+incompatible
@@ -7,3 +9,7 @@ require (
 	github.com/piprate/json-gold v0.1.1
 	github.com/satori/go.uuid v1.2.0
 )
+
+// json-gold v0.1.1 has no go.mod, so the packages it imports have to be
+// required here for the build to find them
+require github.com/pquerna/cachecontrol v0.0.0-20180517163645-1555304b9b35 // indirect
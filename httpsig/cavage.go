@@ -0,0 +1,202 @@
+package httpsig
+
+import (
+	"crypto"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SignCavage signs req with a draft-cavage Signature header. A Date header
+// is added if missing, and a Digest header is added when body is not empty
+func SignCavage(req *http.Request, body []byte, keyID string, signer crypto.Signer) error {
+	if req.Header.Get("Date") == "" {
+		req.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	}
+
+	headers := []string{"(request-target)", "host", "date"}
+	if len(body) > 0 {
+		req.Header.Set("Digest", cavageDigest(body))
+		headers = append(headers, "digest")
+	}
+
+	return signCavageHeaders(req, headers, keyID, signer)
+}
+
+// signCavageHeaders signs the given headers of req with a draft-cavage
+// Signature header
+func signCavageHeaders(req *http.Request, headers []string, keyID string, signer crypto.Signer) error {
+	signingString, err := cavageSigningString(req, headers)
+	if err != nil {
+		return err
+	}
+
+	sig, err := sign(signer, "rsa-sha256", []byte(signingString))
+	if err != nil {
+		return fmt.Errorf("could not sign request: %v", err)
+	}
+
+	req.Header.Set("Signature", fmt.Sprintf(
+		`keyId="%s",algorithm="rsa-sha256",headers="%s",signature="%s"`,
+		keyID,
+		strings.Join(headers, " "),
+		encodeSig(sig),
+	))
+	return nil
+}
+
+// cavageSignature is a parsed draft-cavage Signature header
+type cavageSignature struct {
+	keyID, algorithm string
+	headers          []string
+	signature        []byte
+	created, expires int64
+}
+
+func parseCavageSignature(header string) (*cavageSignature, error) {
+	params := make(map[string]string)
+	rest := strings.TrimSpace(header)
+	for rest != "" {
+		name, value, ok := strings.Cut(rest, "=")
+		if !ok {
+			return nil, fmt.Errorf("%w: expected name=value in %q", ErrMalformedSignature, rest)
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		value = strings.TrimSpace(value)
+
+		if strings.HasPrefix(value, `"`) {
+			end := strings.Index(value[1:], `"`)
+			if end < 0 {
+				return nil, fmt.Errorf("%w: unterminated string", ErrMalformedSignature)
+			}
+			params[name] = value[1 : end+1]
+			value = value[end+2:]
+		} else {
+			end := strings.Index(value, ",")
+			if end < 0 {
+				end = len(value)
+			}
+			params[name] = strings.TrimSpace(value[:end])
+			value = value[end:]
+		}
+
+		rest = strings.TrimPrefix(strings.TrimSpace(value), ",")
+		rest = strings.TrimSpace(rest)
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(params["signature"])
+	if err != nil || len(sig) == 0 || params["keyid"] == "" {
+		return nil, fmt.Errorf("%w: missing keyId or signature", ErrMalformedSignature)
+	}
+
+	headers := []string{"date"}
+	if params["headers"] != "" {
+		headers = strings.Fields(strings.ToLower(params["headers"]))
+	}
+
+	parsed := &cavageSignature{
+		keyID:     params["keyid"],
+		algorithm: strings.ToLower(params["algorithm"]),
+		headers:   headers,
+		signature: sig,
+	}
+
+	for name, dest := range map[string]*int64{"created": &parsed.created, "expires": &parsed.expires} {
+		if params[name] == "" {
+			continue
+		}
+		_, err := fmt.Sscanf(params[name], "%d", dest)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid %s", ErrMalformedSignature, name)
+		}
+	}
+
+	return parsed, nil
+}
+
+func cavageSigningString(req *http.Request, headers []string) (string, error) {
+	lines := make([]string, 0, len(headers))
+	for _, name := range headers {
+		switch name {
+		case "(request-target)":
+			lines = append(lines, fmt.Sprintf(
+				"(request-target): %s %s", strings.ToLower(req.Method), req.URL.RequestURI(),
+			))
+		case "(created)", "(expires)":
+			// these are filled in by verifyCavage since they come from the signature
+			return "", fmt.Errorf("%w: %s must be resolved before signing", ErrMalformedSignature, name)
+		default:
+			value, ok := headerValue(req, name)
+			if !ok {
+				return "", fmt.Errorf("%w: signed header %s is missing", ErrMalformedSignature, name)
+			}
+			lines = append(lines, name+": "+value)
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func (v *Verifier) verifyCavage(req *http.Request, body []byte) (string, error) {
+	parsed, err := parseCavageSignature(req.Header.Get("Signature"))
+	if err != nil {
+		return "", err
+	}
+
+	lines := make([]string, 0, len(parsed.headers))
+	signed := make(map[string]bool)
+	for _, name := range parsed.headers {
+		signed[name] = true
+		switch name {
+		case "(created)":
+			lines = append(lines, fmt.Sprintf("(created): %d", parsed.created))
+		case "(expires)":
+			lines = append(lines, fmt.Sprintf("(expires): %d", parsed.expires))
+		default:
+			line, err := cavageSigningString(req, []string{name})
+			if err != nil {
+				return "", err
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	// like @method and @target-uri for RFC 9421, the target must be signed
+	// so a signature cannot be replayed to another host or path
+	for _, required := range []string{"(request-target)", "host"} {
+		if !signed[required] {
+			return "", fmt.Errorf("%w: %s is not signed", ErrMalformedSignature, required)
+		}
+	}
+
+	signedAt := time.Unix(parsed.created, 0)
+	if !signed["(created)"] {
+		if !signed["date"] {
+			return "", fmt.Errorf("%w: neither date nor (created) are signed", ErrMalformedSignature)
+		}
+		signedAt, err = http.ParseTime(req.Header.Get("Date"))
+		if err != nil {
+			return "", fmt.Errorf("%w: invalid date header", ErrMalformedSignature)
+		}
+	}
+	if !v.fresh(signedAt) || (parsed.expires != 0 && v.now().After(time.Unix(parsed.expires, 0))) {
+		return "", ErrSignatureExpired
+	}
+
+	if len(body) > 0 {
+		if !signed["digest"] {
+			return "", fmt.Errorf("%w: digest is not signed", ErrMalformedSignature)
+		}
+		err = checkCavageDigest(req.Header.Get("Digest"), body)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	err = v.check(parsed.keyID, parsed.algorithm, []byte(strings.Join(lines, "\n")), parsed.signature)
+	if err != nil {
+		return "", err
+	}
+	return parsed.keyID, nil
+}
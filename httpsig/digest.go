@@ -0,0 +1,98 @@
+package httpsig
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// cavageDigest returns a draft-cavage Digest header value for body
+func cavageDigest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return "SHA-256=" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// contentDigest returns an RFC 9530 Content-Digest header value for body
+func contentDigest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return "sha-256=:" + base64.StdEncoding.EncodeToString(sum[:]) + ":"
+}
+
+// checkCavageDigest checks a Digest header against body. At least one
+// supported digest must be present and all supported digests must match
+func checkCavageDigest(header string, body []byte) error {
+	checked := false
+	for _, part := range strings.Split(header, ",") {
+		alg, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+
+		var expected []byte
+		switch strings.ToUpper(alg) {
+		case "SHA-256":
+			sum := sha256.Sum256(body)
+			expected = sum[:]
+		case "SHA-512":
+			sum := sha512.Sum512(body)
+			expected = sum[:]
+		default:
+			continue
+		}
+
+		if !digestEqual(value, expected) {
+			return ErrDigestMismatch
+		}
+		checked = true
+	}
+
+	if !checked {
+		return fmt.Errorf("%w: no supported digest algorithm", ErrDigestMismatch)
+	}
+	return nil
+}
+
+// checkContentDigest checks an RFC 9530 Content-Digest header against body
+func checkContentDigest(header string, body []byte) error {
+	checked := false
+	for _, part := range strings.Split(header, ",") {
+		alg, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok || len(value) < 2 || value[0] != ':' || value[len(value)-1] != ':' {
+			continue
+		}
+		value = value[1 : len(value)-1]
+
+		var expected []byte
+		switch strings.ToLower(alg) {
+		case "sha-256":
+			sum := sha256.Sum256(body)
+			expected = sum[:]
+		case "sha-512":
+			sum := sha512.Sum512(body)
+			expected = sum[:]
+		default:
+			continue
+		}
+
+		if !digestEqual(value, expected) {
+			return ErrDigestMismatch
+		}
+		checked = true
+	}
+
+	if !checked {
+		return fmt.Errorf("%w: no supported digest algorithm", ErrDigestMismatch)
+	}
+	return nil
+}
+
+func digestEqual(encoded string, expected []byte) bool {
+	actual, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(actual, expected) == 1
+}
@@ -0,0 +1,97 @@
+package httpsig
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+)
+
+// ErrDocumentTooLarge is returned when a fetched document exceeds the size
+// limit
+var ErrDocumentTooLarge = errors.New("fetched document is too large")
+
+// activityStreamsAccept asks for ActivityStreams documents
+const activityStreamsAccept = `application/activity+json, application/ld+json; profile="https://www.w3.org/ns/activitystreams"`
+
+// SignedDocumentFetcher returns a DocumentFetcher which dereferences IRIs
+// with GET requests signed by signer, as servers using authorized fetch
+// require. Like deliveries, fetches try RFC 9421 first and fall back to
+// draft-cavage when the server answers 401, unless schemes knows which one
+// the host accepts. Documents larger than maxSize bytes are rejected
+func SignedDocumentFetcher(client *http.Client, signer *Signer, schemes *HostSchemes, maxSize int64) DocumentFetcher {
+	return func(iri string) (map[string]interface{}, error) {
+		target, err := url.Parse(iri)
+		if err != nil {
+			return nil, err
+		}
+
+		order := []Scheme{SchemeRFC9421, SchemeCavage}
+		if schemes != nil {
+			if known, ok := schemes.Get(target.Host); ok && known == SchemeCavage {
+				order = []Scheme{SchemeCavage, SchemeRFC9421}
+			}
+		}
+
+		for _, scheme := range order {
+			scheme := scheme
+			doc, status, err := getDocument(client, iri, maxSize, func(req *http.Request) error {
+				return signer.Sign(req, nil, scheme)
+			})
+			if status == http.StatusUnauthorized {
+				continue
+			}
+			if err == nil && schemes != nil {
+				schemes.Set(target.Host, scheme)
+			}
+			return doc, err
+		}
+		return nil, fmt.Errorf("fetching %s was unauthorized", iri)
+	}
+}
+
+// getDocument fetches the JSON document at iri, letting sign sign the
+// request when it is not nil. It returns the response status along with
+// the document
+func getDocument(client *http.Client, iri string, maxSize int64, sign func(*http.Request) error) (map[string]interface{}, int, error) {
+	req, err := http.NewRequest("GET", iri, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Accept", activityStreamsAccept)
+
+	if sign != nil {
+		err = sign(req)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, resp.StatusCode, fmt.Errorf("fetching %s returned %d", iri, resp.StatusCode)
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, resp.StatusCode, err
+	}
+	if int64(len(body)) > maxSize {
+		return nil, resp.StatusCode, fmt.Errorf("%w: %s is larger than %d bytes", ErrDocumentTooLarge, iri, maxSize)
+	}
+
+	var doc map[string]interface{}
+	err = json.Unmarshal(body, &doc)
+	if err != nil {
+		return nil, resp.StatusCode, fmt.Errorf("could not parse %s: %v", iri, err)
+	}
+	return doc, resp.StatusCode, nil
+}
@@ -0,0 +1,55 @@
+package httpsig
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/Koshroy/turnover/keystore"
+)
+
+func TestSignedDocumentFetcher(t *testing.T) {
+	t.Parallel()
+
+	// the server only accepts draft-cavage signatures
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Signature-Input") != "" || r.Header.Get("Signature") == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if r.URL.Path == "/large" {
+			_, _ = w.Write([]byte(`{"content": "` + strings.Repeat("a", 256) + `"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"id": "` + "http://" + r.Host + r.URL.Path + `", "type": "Note"}`))
+	}))
+	defer server.Close()
+
+	signer := NewSigner(keystore.MockStore(), func(id string) string {
+		return "https://relay.example.com/actor#" + id
+	})
+	schemes := NewHostSchemes()
+	fetch := SignedDocumentFetcher(server.Client(), signer, schemes, 128)
+
+	doc, err := fetch(server.URL + "/notes/1")
+	if err != nil {
+		t.Fatalf("could not fetch document: %v", err)
+	}
+	if doc["type"] != "Note" {
+		t.Errorf("expected a Note got %v", doc)
+	}
+
+	host, _ := url.Parse(server.URL)
+	if scheme, ok := schemes.Get(host.Host); !ok || scheme != SchemeCavage {
+		t.Errorf("expected the host to be known to accept draft-cavage got %v", scheme)
+	}
+
+	_, err = fetch(server.URL + "/large")
+	if !errors.Is(err, ErrDocumentTooLarge) {
+		t.Errorf("expected ErrDocumentTooLarge got %v", err)
+	}
+}
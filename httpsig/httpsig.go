@@ -0,0 +1,165 @@
+package httpsig
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Scheme is an HTTP signature scheme
+type Scheme int
+
+const (
+	// SchemeRFC9421 is RFC 9421 HTTP Message Signatures
+	SchemeRFC9421 Scheme = iota
+	// SchemeCavage is draft-cavage-http-signatures
+	SchemeCavage
+)
+
+func (s Scheme) String() string {
+	switch s {
+	case SchemeRFC9421:
+		return "rfc9421"
+	case SchemeCavage:
+		return "cavage"
+	default:
+		return "unknown"
+	}
+}
+
+// ErrNoSignature is returned when a request carries no signature
+var ErrNoSignature = errors.New("request is not signed")
+
+// ErrInvalidSignature is returned when a signature does not verify
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// ErrMalformedSignature is returned when signature headers cannot be parsed
+var ErrMalformedSignature = errors.New("malformed signature")
+
+// ErrDigestMismatch is returned when the body digest does not match the body
+var ErrDigestMismatch = errors.New("body digest does not match")
+
+// ErrSignatureExpired is returned when a signature was made too long ago
+var ErrSignatureExpired = errors.New("signature is too old or in the future")
+
+// ErrUnsupportedAlgorithm is returned for unknown signature algorithms or key types
+var ErrUnsupportedAlgorithm = errors.New("unsupported signature algorithm")
+
+// sign signs data with signer using the named algorithm. Both RFC 9421 and
+// draft-cavage algorithm names are understood
+func sign(signer crypto.Signer, alg string, data []byte) ([]byte, error) {
+	switch alg {
+	case "ed25519":
+		return signer.Sign(rand.Reader, data, crypto.Hash(0))
+	case "rsa-v1_5-sha256", "rsa-sha256", "hs2019":
+		digest := sha256.Sum256(data)
+		return signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	case "rsa-pss-sha512":
+		digest := sha512.Sum512(data)
+		return signer.Sign(rand.Reader, digest[:], &rsa.PSSOptions{
+			SaltLength: 64,
+			Hash:       crypto.SHA512,
+		})
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, alg)
+	}
+}
+
+// verify checks sig over data with pubKey. An empty or "hs2019" algorithm
+// is resolved from the key type
+func verify(pubKey crypto.PublicKey, alg string, data, sig []byte) error {
+	switch key := pubKey.(type) {
+	case ed25519.PublicKey:
+		if alg != "" && alg != "ed25519" && alg != "hs2019" {
+			return fmt.Errorf("%w: %s with Ed25519 key", ErrUnsupportedAlgorithm, alg)
+		}
+		if !ed25519.Verify(key, data, sig) {
+			return ErrInvalidSignature
+		}
+		return nil
+	case *rsa.PublicKey:
+		switch alg {
+		case "", "rsa-v1_5-sha256", "rsa-sha256", "hs2019":
+			digest := sha256.Sum256(data)
+			if rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig) != nil {
+				return ErrInvalidSignature
+			}
+			return nil
+		case "rsa-pss-sha512":
+			digest := sha512.Sum512(data)
+			err := rsa.VerifyPSS(key, crypto.SHA512, digest[:], sig, &rsa.PSSOptions{
+				SaltLength: 64,
+				Hash:       crypto.SHA512,
+			})
+			if err != nil {
+				return ErrInvalidSignature
+			}
+			return nil
+		default:
+			return fmt.Errorf("%w: %s with RSA key", ErrUnsupportedAlgorithm, alg)
+		}
+	default:
+		return fmt.Errorf("%w: key type %T", ErrUnsupportedAlgorithm, pubKey)
+	}
+}
+
+// HostSchemes remembers which signature scheme each host accepted
+type HostSchemes struct {
+	schemes map[string]Scheme
+	sync.RWMutex
+}
+
+// NewHostSchemes returns a new HostSchemes instance
+func NewHostSchemes() *HostSchemes {
+	return &HostSchemes{
+		schemes: make(map[string]Scheme),
+	}
+}
+
+// Get returns the scheme last accepted by host
+func (h *HostSchemes) Get(host string) (Scheme, bool) {
+	h.RLock()
+	defer h.RUnlock()
+	scheme, ok := h.schemes[strings.ToLower(host)]
+	return scheme, ok
+}
+
+// Set records that host accepted scheme
+func (h *HostSchemes) Set(host string, scheme Scheme) {
+	h.Lock()
+	defer h.Unlock()
+	h.schemes[strings.ToLower(host)] = scheme
+}
+
+func encodeSig(sig []byte) string {
+	return base64.StdEncoding.EncodeToString(sig)
+}
+
+func headerValue(req *http.Request, name string) (string, bool) {
+	if strings.EqualFold(name, "host") {
+		if req.Host != "" {
+			return req.Host, true
+		}
+		return req.URL.Host, req.URL.Host != ""
+	}
+
+	values, ok := req.Header[http.CanonicalHeaderKey(name)]
+	if !ok {
+		return "", false
+	}
+
+	trimmed := make([]string, len(values))
+	for i, value := range values {
+		trimmed[i] = strings.TrimSpace(value)
+	}
+	return strings.Join(trimmed, ", "), true
+}
@@ -0,0 +1,215 @@
+package httpsig
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Koshroy/turnover/keystore"
+)
+
+type staticKeys map[string]crypto.PublicKey
+
+func (s staticKeys) PublicKey(keyID string) (crypto.PublicKey, error) {
+	key, ok := s[keyID]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	return key, nil
+}
+
+// signedServerRequest signs an outgoing request and returns the request as
+// a server receiving it would see it
+func signedServerRequest(t *testing.T, body []byte, sign func(*http.Request) error) *http.Request {
+	out, err := http.NewRequest("POST", "https://relay.example.com/inbox", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("could not create request: %v", err)
+	}
+
+	err = sign(out)
+	if err != nil {
+		t.Fatalf("could not sign request: %v", err)
+	}
+
+	in := httptest.NewRequest("POST", "/inbox", bytes.NewReader(body))
+	in.Host = "relay.example.com"
+	in.Header = out.Header.Clone()
+	return in
+}
+
+func TestSignVerify(t *testing.T) {
+	t.Parallel()
+
+	store := keystore.MockStore()
+	rsaKey := store.PrivKey()
+	edPub, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate Ed25519 key: %v", err)
+	}
+
+	keys := staticKeys{
+		"https://sally.example.org/actor#main-key":    &rsaKey.PublicKey,
+		"https://sally.example.org/actor#ed25519-key": edPub,
+	}
+	body := []byte(`{"type":"Create"}`)
+
+	var tests = []struct {
+		name   string
+		scheme Scheme
+		sign   func(*http.Request) error
+	}{
+		{"cavage rsa", SchemeCavage, func(req *http.Request) error {
+			return SignCavage(req, body, "https://sally.example.org/actor#main-key", rsaKey)
+		}},
+		{"rfc9421 rsa", SchemeRFC9421, func(req *http.Request) error {
+			return SignRFC9421(req, body, "https://sally.example.org/actor#main-key", keystore.AlgorithmRSAv15SHA256, rsaKey)
+		}},
+		{"rfc9421 rsa-pss", SchemeRFC9421, func(req *http.Request) error {
+			return SignRFC9421(req, body, "https://sally.example.org/actor#main-key", "rsa-pss-sha512", rsaKey)
+		}},
+		{"rfc9421 ed25519", SchemeRFC9421, func(req *http.Request) error {
+			return SignRFC9421(req, body, "https://sally.example.org/actor#ed25519-key", keystore.AlgorithmEd25519, edPriv)
+		}},
+	}
+
+	for _, tt := range tests {
+		var tt = tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			verifier := NewVerifier("https", keys)
+			req := signedServerRequest(t, body, tt.sign)
+			_, scheme, err := verifier.Verify(req, body)
+			if err != nil {
+				t.Fatalf("could not verify signature: %v", err)
+			}
+
+			if scheme != tt.scheme {
+				t.Errorf("expected scheme %s got %s", tt.scheme, scheme)
+			}
+
+			tampered := []byte(`{"type":"Delete"}`)
+			_, _, err = verifier.Verify(req, tampered)
+			if !errors.Is(err, ErrDigestMismatch) {
+				t.Errorf("expected digest mismatch for tampered body got %v", err)
+			}
+
+			verifier.now = func() time.Time { return time.Now().Add(15 * time.Minute) }
+			_, _, err = verifier.Verify(req, body)
+			if !errors.Is(err, ErrSignatureExpired) {
+				t.Errorf("expected expired signature got %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifyRejectsWrongKey(t *testing.T) {
+	t.Parallel()
+
+	edPub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate Ed25519 key: %v", err)
+	}
+
+	store := keystore.MockStore()
+	keys := staticKeys{"https://sally.example.org/actor#main-key": edPub}
+	req := signedServerRequest(t, nil, func(req *http.Request) error {
+		return SignCavage(req, nil, "https://sally.example.org/actor#main-key", store.PrivKey())
+	})
+
+	_, _, err = NewVerifier("https", keys).Verify(req, nil)
+	if err == nil {
+		t.Errorf("expected verification with the wrong key to fail")
+	}
+}
+
+func TestVerifyCavageRequiresTarget(t *testing.T) {
+	t.Parallel()
+
+	store := keystore.MockStore()
+	rsaKey := store.PrivKey()
+	keys := staticKeys{"https://sally.example.org/actor#main-key": &rsaKey.PublicKey}
+	body := []byte(`{"type":"Create"}`)
+
+	var tests = []struct {
+		name    string
+		headers []string
+		valid   bool
+	}{
+		{"request target and host", []string{"(request-target)", "host", "date", "digest"}, true},
+		{"no request target", []string{"host", "date", "digest"}, false},
+		{"no host", []string{"(request-target)", "date", "digest"}, false},
+		{"only date and digest", []string{"date", "digest"}, false},
+	}
+
+	for _, tt := range tests {
+		req := signedServerRequest(t, body, func(req *http.Request) error {
+			req.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+			req.Header.Set("Digest", cavageDigest(body))
+			return signCavageHeaders(req, tt.headers, "https://sally.example.org/actor#main-key", rsaKey)
+		})
+
+		_, _, err := NewVerifier("https", keys).Verify(req, body)
+		if tt.valid && err != nil {
+			t.Errorf("%s: could not verify signature: %v", tt.name, err)
+		}
+		if !tt.valid && !errors.Is(err, ErrMalformedSignature) {
+			t.Errorf("%s: expected ErrMalformedSignature got %v", tt.name, err)
+		}
+	}
+}
+
+func TestVerifyUnsigned(t *testing.T) {
+	t.Parallel()
+
+	req := httptest.NewRequest("POST", "/inbox", nil)
+	_, _, err := NewVerifier("https", staticKeys{}).Verify(req, nil)
+	if !errors.Is(err, ErrNoSignature) {
+		t.Errorf("expected ErrNoSignature got %v", err)
+	}
+}
+
+func TestPublicKeyFromDocument(t *testing.T) {
+	t.Parallel()
+
+	edPub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate Ed25519 key: %v", err)
+	}
+
+	doc := map[string]interface{}{
+		"id": "https://sally.example.org/actor",
+		"publicKey": map[string]interface{}{
+			"id":           "https://sally.example.org/actor#main-key",
+			"publicKeyPem": keystore.MockPubKey,
+		},
+		"assertionMethod": []interface{}{
+			map[string]interface{}{
+				"id":                 "https://sally.example.org/actor#ed25519-key",
+				"type":               "Multikey",
+				"publicKeyMultibase": keystore.EncodeMultikey(edPub),
+			},
+		},
+	}
+
+	for _, keyID := range []string{
+		"https://sally.example.org/actor#main-key",
+		"https://sally.example.org/actor#ed25519-key",
+	} {
+		_, err := PublicKeyFromDocument(doc, keyID)
+		if err != nil {
+			t.Errorf("could not find %s: %v", keyID, err)
+		}
+	}
+
+	_, err = PublicKeyFromDocument(doc, "https://sally.example.org/actor#other-key")
+	if !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("expected ErrKeyNotFound got %v", err)
+	}
+}
@@ -0,0 +1,140 @@
+package httpsig
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/Koshroy/turnover/keystore"
+)
+
+const maxKeyDocumentSz = 1 << 20 // 1 MB
+
+// ErrKeyNotFound is returned when a key ID does not resolve to a usable key
+var ErrKeyNotFound = errors.New("key not found")
+
+// DocumentFetcher dereferences an IRI into a JSON document
+type DocumentFetcher func(iri string) (map[string]interface{}, error)
+
+// HTTPDocumentFetcher returns a DocumentFetcher which dereferences IRIs with
+// unsigned GET requests
+func HTTPDocumentFetcher(client *http.Client) DocumentFetcher {
+	return func(iri string) (map[string]interface{}, error) {
+		doc, _, err := getDocument(client, iri, maxKeyDocumentSz, nil)
+		return doc, err
+	}
+}
+
+type cachedKey struct {
+	key     crypto.PublicKey
+	fetched time.Time
+}
+
+// KeyFetcher is a KeySource which dereferences key IDs and caches the keys
+type KeyFetcher struct {
+	fetch DocumentFetcher
+	ttl   time.Duration
+
+	mu    sync.RWMutex
+	cache map[string]cachedKey
+}
+
+// NewKeyFetcher creates a new KeyFetcher which caches keys for ttl
+func NewKeyFetcher(fetch DocumentFetcher, ttl time.Duration) *KeyFetcher {
+	return &KeyFetcher{
+		fetch: fetch,
+		ttl:   ttl,
+		cache: make(map[string]cachedKey),
+	}
+}
+
+// PublicKey returns the public key identified by keyID
+func (f *KeyFetcher) PublicKey(keyID string) (crypto.PublicKey, error) {
+	f.mu.RLock()
+	cached, ok := f.cache[keyID]
+	f.mu.RUnlock()
+	if ok && time.Since(cached.fetched) < f.ttl {
+		return cached.key, nil
+	}
+
+	keyURL, err := url.Parse(keyID)
+	if err != nil || !keyURL.IsAbs() {
+		return nil, fmt.Errorf("%w: invalid key ID %s", ErrKeyNotFound, keyID)
+	}
+	keyURL.Fragment = ""
+
+	doc, err := f.fetch(keyURL.String())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrKeyNotFound, err)
+	}
+
+	key, err := PublicKeyFromDocument(doc, keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	f.cache[keyID] = cachedKey{key: key, fetched: time.Now()}
+	f.mu.Unlock()
+	return key, nil
+}
+
+// Invalidate drops a cached key
+func (f *KeyFetcher) Invalidate(keyID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.cache, keyID)
+}
+
+// PublicKeyFromDocument finds the key with keyID in an actor or key
+// document, looking at publicKey entries and FEP-521a Multikey entries
+// under assertionMethod
+func PublicKeyFromDocument(doc map[string]interface{}, keyID string) (crypto.PublicKey, error) {
+	candidates := []map[string]interface{}{doc}
+	for _, property := range []string{"publicKey", "assertionMethod"} {
+		switch value := doc[property].(type) {
+		case map[string]interface{}:
+			candidates = append(candidates, value)
+		case []interface{}:
+			for _, item := range value {
+				if node, ok := item.(map[string]interface{}); ok {
+					candidates = append(candidates, node)
+				}
+			}
+		}
+	}
+
+	for _, node := range candidates {
+		if id, _ := node["id"].(string); id != keyID {
+			continue
+		}
+
+		if pemStr, ok := node["publicKeyPem"].(string); ok {
+			return parsePublicKeyPem([]byte(pemStr))
+		}
+
+		if multibase, ok := node["publicKeyMultibase"].(string); ok {
+			return keystore.DecodeMultikey(multibase)
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s is not in the fetched document", ErrKeyNotFound, keyID)
+}
+
+func parsePublicKeyPem(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%w: no PEM block in publicKeyPem", ErrKeyNotFound)
+	}
+
+	if block.Type == "RSA PUBLIC KEY" {
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
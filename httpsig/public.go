@@ -0,0 +1,62 @@
+package httpsig
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// ErrNonPublicAddress is returned when a request would connect to an
+// address outside the public internet
+var ErrNonPublicAddress = errors.New("address is not public")
+
+// NewPublicClient returns a http.Client with timeout which refuses to
+// connect to loopback, private, link-local, unspecified and multicast
+// addresses, so IRIs sent by remote servers cannot make the relay reach
+// services on its own network. The check runs on the address the dialer
+// resolved, so it also covers names pointing at such addresses and
+// redirects. Proxies are not used, since the check would only see the
+// proxy address
+func NewPublicClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: dialPublic,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+	}
+}
+
+// dialPublic is a net.Dialer Control hook rejecting non-public addresses
+func dialPublic(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || !isPublic(ip) {
+		return fmt.Errorf("%w: %s", ErrNonPublicAddress, host)
+	}
+	return nil
+}
+
+// isPublic reports whether ip is a public unicast address
+func isPublic(ip net.IP) bool {
+	return !ip.IsLoopback() &&
+		!ip.IsPrivate() &&
+		!ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() &&
+		!ip.IsMulticast() &&
+		!ip.IsUnspecified()
+}
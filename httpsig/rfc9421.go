@@ -0,0 +1,257 @@
+package httpsig
+
+import (
+	"crypto"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const signatureLabel = "sig1"
+
+// SignRFC9421 signs req with RFC 9421 Signature-Input and Signature headers
+// covering the method, target URI and, when body is not empty, an RFC 9530
+// Content-Digest
+func SignRFC9421(req *http.Request, body []byte, keyID, alg string, signer crypto.Signer) error {
+	components := []string{"@method", "@target-uri"}
+	if len(body) > 0 {
+		req.Header.Set("Content-Digest", contentDigest(body))
+		components = append(components, "content-digest")
+	}
+
+	quoted := make([]string, len(components))
+	for i, component := range components {
+		quoted[i] = strconv.Quote(component)
+	}
+	params := fmt.Sprintf(
+		"(%s);created=%d;keyid=%s;alg=%s",
+		strings.Join(quoted, " "),
+		time.Now().Unix(),
+		strconv.Quote(keyID),
+		strconv.Quote(alg),
+	)
+
+	base, err := signatureBase(req, req.URL.String(), components, params)
+	if err != nil {
+		return err
+	}
+
+	sig, err := sign(signer, alg, []byte(base))
+	if err != nil {
+		return fmt.Errorf("could not sign request: %v", err)
+	}
+
+	req.Header.Set("Signature-Input", signatureLabel+"="+params)
+	req.Header.Set("Signature", signatureLabel+"=:"+encodeSig(sig)+":")
+	return nil
+}
+
+// signatureBase builds the RFC 9421 signature base for the covered
+// components, ending with the serialized signature parameters
+func signatureBase(req *http.Request, targetURI string, components []string, params string) (string, error) {
+	var b strings.Builder
+	for _, component := range components {
+		var value string
+		switch component {
+		case "@method":
+			value = req.Method
+		case "@target-uri":
+			value = targetURI
+		case "@authority":
+			value = strings.ToLower(req.Host)
+			if value == "" {
+				value = strings.ToLower(req.URL.Host)
+			}
+		case "@path":
+			value = req.URL.EscapedPath()
+		case "@query":
+			value = "?" + req.URL.RawQuery
+		default:
+			if strings.HasPrefix(component, "@") {
+				return "", fmt.Errorf("%w: unsupported component %s", ErrMalformedSignature, component)
+			}
+			var ok bool
+			value, ok = headerValue(req, component)
+			if !ok {
+				return "", fmt.Errorf("%w: covered field %s is missing", ErrMalformedSignature, component)
+			}
+		}
+		fmt.Fprintf(&b, "%q: %s\n", component, value)
+	}
+	fmt.Fprintf(&b, "%q: %s", "@signature-params", params)
+	return b.String(), nil
+}
+
+// signatureInput is a parsed member of the Signature-Input dictionary
+type signatureInput struct {
+	label      string
+	components []string
+	params     map[string]string
+	// raw is the serialized inner list and parameters as received
+	raw string
+}
+
+// parseSignatureInput parses the Signature-Input dictionary. Only the
+// subset of structured fields used by signature parameters is supported
+func parseSignatureInput(header string) ([]signatureInput, error) {
+	inputs := make([]signatureInput, 0, 1)
+	for _, member := range splitDictionary(header) {
+		label, raw, ok := strings.Cut(member, "=")
+		if !ok || !strings.HasPrefix(raw, "(") {
+			return nil, fmt.Errorf("%w: invalid Signature-Input member", ErrMalformedSignature)
+		}
+
+		end := strings.Index(raw, ")")
+		if end < 0 {
+			return nil, fmt.Errorf("%w: unterminated inner list", ErrMalformedSignature)
+		}
+
+		components := make([]string, 0)
+		for _, item := range strings.Fields(raw[1:end]) {
+			component, err := strconv.Unquote(item)
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid component %s", ErrMalformedSignature, item)
+			}
+			components = append(components, strings.ToLower(component))
+		}
+
+		params := make(map[string]string)
+		for _, param := range strings.Split(raw[end+1:], ";") {
+			name, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if !ok {
+				continue
+			}
+			if unquoted, err := strconv.Unquote(value); err == nil {
+				value = unquoted
+			}
+			params[name] = value
+		}
+
+		inputs = append(inputs, signatureInput{
+			label:      strings.TrimSpace(label),
+			components: components,
+			params:     params,
+			raw:        raw,
+		})
+	}
+	return inputs, nil
+}
+
+// parseSignatures parses the Signature dictionary into labelled signatures
+func parseSignatures(header string) (map[string][]byte, error) {
+	sigs := make(map[string][]byte)
+	for _, member := range splitDictionary(header) {
+		label, value, ok := strings.Cut(member, "=")
+		if !ok || len(value) < 2 || value[0] != ':' || value[len(value)-1] != ':' {
+			return nil, fmt.Errorf("%w: invalid Signature member", ErrMalformedSignature)
+		}
+
+		sig, err := base64.StdEncoding.DecodeString(value[1 : len(value)-1])
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid signature encoding", ErrMalformedSignature)
+		}
+		sigs[strings.TrimSpace(label)] = sig
+	}
+	return sigs, nil
+}
+
+// splitDictionary splits a structured field dictionary on commas that are
+// not inside strings or inner lists
+func splitDictionary(header string) []string {
+	members := make([]string, 0, 1)
+	depth := 0
+	inString := false
+	start := 0
+	for i := 0; i < len(header); i++ {
+		switch c := header[i]; {
+		case c == '\\' && inString:
+			i++
+		case c == '"':
+			inString = !inString
+		case c == '(' && !inString:
+			depth++
+		case c == ')' && !inString:
+			depth--
+		case c == ',' && !inString && depth == 0:
+			members = append(members, strings.TrimSpace(header[start:i]))
+			start = i + 1
+		}
+	}
+	if member := strings.TrimSpace(header[start:]); member != "" {
+		members = append(members, member)
+	}
+	return members
+}
+
+func (v *Verifier) verifyRFC9421(req *http.Request, body []byte) (string, error) {
+	inputs, err := parseSignatureInput(req.Header.Get("Signature-Input"))
+	if err != nil {
+		return "", err
+	}
+
+	sigs, err := parseSignatures(req.Header.Get("Signature"))
+	if err != nil {
+		return "", err
+	}
+
+	if len(inputs) == 0 {
+		return "", fmt.Errorf("%w: empty Signature-Input", ErrMalformedSignature)
+	}
+
+	// Only the first signature is checked, which is the one a sender
+	// signing for us will have added
+	input := inputs[0]
+	sig, ok := sigs[input.label]
+	if !ok {
+		return "", fmt.Errorf("%w: no signature labelled %s", ErrMalformedSignature, input.label)
+	}
+
+	keyID := input.params["keyid"]
+	if keyID == "" {
+		return "", fmt.Errorf("%w: missing keyid", ErrMalformedSignature)
+	}
+
+	covered := make(map[string]bool)
+	for _, component := range input.components {
+		covered[component] = true
+	}
+	if !covered["@method"] || !(covered["@target-uri"] || covered["@path"]) {
+		return "", fmt.Errorf("%w: method and target must be covered", ErrMalformedSignature)
+	}
+
+	created, err := strconv.ParseInt(input.params["created"], 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("%w: missing or invalid created", ErrMalformedSignature)
+	}
+	if !v.fresh(time.Unix(created, 0)) {
+		return "", ErrSignatureExpired
+	}
+	if expires, err := strconv.ParseInt(input.params["expires"], 10, 64); err == nil &&
+		v.now().After(time.Unix(expires, 0)) {
+		return "", ErrSignatureExpired
+	}
+
+	if len(body) > 0 {
+		if !covered["content-digest"] {
+			return "", fmt.Errorf("%w: content-digest is not covered", ErrMalformedSignature)
+		}
+		err = checkContentDigest(req.Header.Get("Content-Digest"), body)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	base, err := signatureBase(req, v.targetURI(req), input.components, input.raw)
+	if err != nil {
+		return "", err
+	}
+
+	err = v.check(keyID, input.params["alg"], []byte(base), sig)
+	if err != nil {
+		return "", err
+	}
+	return keyID, nil
+}
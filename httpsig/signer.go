@@ -0,0 +1,33 @@
+package httpsig
+
+import (
+	"net/http"
+
+	"github.com/Koshroy/turnover/keystore"
+)
+
+// Signer signs outgoing requests with the keys of a keystore.Store
+type Signer struct {
+	store *keystore.Store
+	keyID func(id string) string
+}
+
+// NewSigner creates a new Signer. keyID maps the ID of a key in the store
+// to the key IRI published on the actor
+func NewSigner(store *keystore.Store, keyID func(id string) string) *Signer {
+	return &Signer{
+		store: store,
+		keyID: keyID,
+	}
+}
+
+// Sign signs req with the given scheme
+func (s *Signer) Sign(req *http.Request, body []byte, scheme Scheme) error {
+	if scheme == SchemeCavage {
+		key := s.store.CavageKey()
+		return SignCavage(req, body, s.keyID(key.ID), key.Signer)
+	}
+
+	key := s.store.RFC9421Key()
+	return SignRFC9421(req, body, s.keyID(key.ID), key.Algorithm, key.Signer)
+}
@@ -0,0 +1,98 @@
+package httpsig
+
+import (
+	"crypto"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// defaultMaxSkew is how far signature times may be from the current time,
+// which bounds how long a captured request can be replayed
+const defaultMaxSkew = 12 * time.Minute
+
+// KeySource looks up the public key for a key ID
+type KeySource interface {
+	PublicKey(keyID string) (crypto.PublicKey, error)
+}
+
+// invalidator is implemented by a KeySource that caches keys, so a key which
+// fails verification can be refetched in case it has been rotated
+type invalidator interface {
+	Invalidate(keyID string)
+}
+
+// Verifier verifies RFC 9421 and draft-cavage signatures on incoming requests
+type Verifier struct {
+	keys   KeySource
+	scheme string
+	// MaxSkew is how far the signature time may be from the current time
+	MaxSkew time.Duration
+	now     func() time.Time
+}
+
+// NewVerifier creates a new Verifier. scheme is used to rebuild the target
+// URI of incoming requests, which only carry a path
+func NewVerifier(scheme string, keys KeySource) *Verifier {
+	return &Verifier{
+		keys:    keys,
+		scheme:  scheme,
+		MaxSkew: defaultMaxSkew,
+		now:     time.Now,
+	}
+}
+
+// Verify checks the signature on req, preferring RFC 9421 when both schemes
+// are present, and returns the ID of the key that signed it
+func (v *Verifier) Verify(req *http.Request, body []byte) (string, Scheme, error) {
+	if req.Header.Get("Signature-Input") != "" {
+		keyID, err := v.verifyRFC9421(req, body)
+		return keyID, SchemeRFC9421, err
+	}
+
+	if req.Header.Get("Signature") != "" {
+		keyID, err := v.verifyCavage(req, body)
+		return keyID, SchemeCavage, err
+	}
+
+	return "", SchemeRFC9421, ErrNoSignature
+}
+
+func (v *Verifier) check(keyID, alg string, data, sig []byte) error {
+	pubKey, err := v.keys.PublicKey(keyID)
+	if err != nil {
+		return err
+	}
+
+	err = verify(pubKey, alg, data, sig)
+	if !errors.Is(err, ErrInvalidSignature) {
+		return err
+	}
+
+	cache, ok := v.keys.(invalidator)
+	if !ok {
+		return err
+	}
+
+	cache.Invalidate(keyID)
+	pubKey, err = v.keys.PublicKey(keyID)
+	if err != nil {
+		return err
+	}
+	return verify(pubKey, alg, data, sig)
+}
+
+func (v *Verifier) fresh(signedAt time.Time) bool {
+	skew := v.now().Sub(signedAt)
+	if skew < 0 {
+		skew = -skew
+	}
+	return skew <= v.MaxSkew
+}
+
+func (v *Verifier) targetURI(req *http.Request) string {
+	if req.URL.IsAbs() {
+		return req.URL.String()
+	}
+	return v.scheme + "://" + req.Host + req.URL.RequestURI()
+}
@@ -0,0 +1,93 @@
+package keystore
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// multicodec prefix for an Ed25519 public key
+var ed25519Multicodec = []byte{0xed, 0x01}
+
+// ErrInvalidMultikey is returned when a publicKeyMultibase value cannot be decoded
+var ErrInvalidMultikey = errors.New("invalid Ed25519 multikey")
+
+// EncodeMultikey encodes an Ed25519 public key as a base58btc multibase
+// string for use as publicKeyMultibase in a FEP-521a Multikey
+func EncodeMultikey(pubKey ed25519.PublicKey) string {
+	data := append(append([]byte{}, ed25519Multicodec...), pubKey...)
+	return "z" + base58Encode(data)
+}
+
+// DecodeMultikey decodes a base58btc publicKeyMultibase value holding an
+// Ed25519 public key
+func DecodeMultikey(multibase string) (ed25519.PublicKey, error) {
+	if len(multibase) < 2 || multibase[0] != 'z' {
+		return nil, fmt.Errorf("%w: only base58btc multibase is supported", ErrInvalidMultikey)
+	}
+
+	data, err := base58Decode(multibase[1:])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMultikey, err)
+	}
+
+	if len(data) != len(ed25519Multicodec)+ed25519.PublicKeySize ||
+		data[0] != ed25519Multicodec[0] || data[1] != ed25519Multicodec[1] {
+		return nil, fmt.Errorf("%w: not an Ed25519 public key", ErrInvalidMultikey)
+	}
+
+	return ed25519.PublicKey(data[len(ed25519Multicodec):]), nil
+}
+
+func base58Encode(data []byte) string {
+	n := new(big.Int).SetBytes(data)
+	radix := big.NewInt(58)
+	mod := new(big.Int)
+
+	out := make([]byte, 0, len(data)*138/100+1)
+	for n.Sign() > 0 {
+		n.DivMod(n, radix, mod)
+		out = append(out, base58Alphabet[mod.Int64()])
+	}
+
+	for _, b := range data {
+		if b != 0 {
+			break
+		}
+		out = append(out, base58Alphabet[0])
+	}
+
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
+
+func base58Decode(s string) ([]byte, error) {
+	n := new(big.Int)
+	radix := big.NewInt(58)
+	for _, c := range []byte(s) {
+		idx := -1
+		for i := 0; i < len(base58Alphabet); i++ {
+			if base58Alphabet[i] == c {
+				idx = i
+				break
+			}
+		}
+		if idx < 0 {
+			return nil, fmt.Errorf("invalid base58 character %q", c)
+		}
+		n.Mul(n, radix)
+		n.Add(n, big.NewInt(int64(idx)))
+	}
+
+	leading := 0
+	for leading < len(s) && s[leading] == base58Alphabet[0] {
+		leading++
+	}
+
+	return append(make([]byte, leading), n.Bytes()...), nil
+}
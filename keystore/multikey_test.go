@@ -0,0 +1,67 @@
+package keystore
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"strings"
+	"testing"
+)
+
+func TestMultikeyRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	pubKey, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate Ed25519 key: %v", err)
+	}
+
+	multibase := EncodeMultikey(pubKey)
+	if !strings.HasPrefix(multibase, "z6Mk") {
+		t.Errorf("expected Ed25519 multikey to start with z6Mk got %s", multibase)
+	}
+
+	decoded, err := DecodeMultikey(multibase)
+	if err != nil {
+		t.Fatalf("could not decode multikey: %v", err)
+	}
+
+	if !bytes.Equal(decoded, pubKey) {
+		t.Errorf("decoded multikey does not match public key")
+	}
+}
+
+func TestDecodeMultikeyErrors(t *testing.T) {
+	t.Parallel()
+
+	for _, multibase := range []string{"", "u6Mk", "z0OIl", "z6Mk"} {
+		_, err := DecodeMultikey(multibase)
+		if err == nil {
+			t.Errorf("expected error decoding %q", multibase)
+		}
+	}
+}
+
+func TestRFC9421KeyPrefersEd25519(t *testing.T) {
+	t.Parallel()
+
+	store := MockStore()
+	if store.RFC9421Key().Algorithm != AlgorithmRSAv15SHA256 {
+		t.Errorf("expected RSA key without an Ed25519 key got %s", store.RFC9421Key().Algorithm)
+	}
+
+	_, privKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate Ed25519 key: %v", err)
+	}
+	store.SetEd25519Key(privKey)
+
+	key := store.RFC9421Key()
+	if key.Algorithm != AlgorithmEd25519 || key.ID != Ed25519KeyID {
+		t.Errorf("expected Ed25519 key got %s %s", key.ID, key.Algorithm)
+	}
+
+	if store.CavageKey().Algorithm != AlgorithmRSASHA256 {
+		t.Errorf("expected cavage signatures to keep using RSA got %s", store.CavageKey().Algorithm)
+	}
+}
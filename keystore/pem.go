@@ -0,0 +1,121 @@
+package keystore
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+// ErrNoPEMBlock is returned when key data does not contain a PEM block
+var ErrNoPEMBlock = errors.New("no PEM block found in key data")
+
+// ErrNotRSAKey is returned when a parsed key is not an RSA key
+var ErrNotRSAKey = errors.New("key is not an RSA key")
+
+// ErrNotEd25519Key is returned when a parsed key is not an Ed25519 key
+var ErrNotEd25519Key = errors.New("key is not an Ed25519 key")
+
+// ErrKeyMismatch is returned when a public key does not belong to the private key
+var ErrKeyMismatch = errors.New("public key does not match private key")
+
+// parsePrivateKey parses a PEM encoded RSA private key in either PKCS#1
+// ("RSA PRIVATE KEY") or PKCS#8 ("PRIVATE KEY") form
+func parsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("could not parse private key: %w", ErrNoPEMBlock)
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		privKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse PKCS#1 private key: %v", err)
+		}
+		return privKey, nil
+	case "PRIVATE KEY":
+		keyBase, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse PKCS#8 private key: %v", err)
+		}
+		privKey, ok := keyBase.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("could not use PKCS#8 private key: %w", ErrNotRSAKey)
+		}
+		return privKey, nil
+	default:
+		return nil, fmt.Errorf("unsupported private key PEM block type %q", block.Type)
+	}
+}
+
+// parsePublicKey parses a PEM encoded RSA public key in either PKIX
+// ("PUBLIC KEY") or PKCS#1 ("RSA PUBLIC KEY") form
+func parsePublicKey(data []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("could not parse public key: %w", ErrNoPEMBlock)
+	}
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		keyBase, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse PKIX public key: %v", err)
+		}
+		pubKey, ok := keyBase.(*rsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("could not use PKIX public key: %w", ErrNotRSAKey)
+		}
+		return pubKey, nil
+	case "RSA PUBLIC KEY":
+		pubKey, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse PKCS#1 public key: %v", err)
+		}
+		return pubKey, nil
+	default:
+		return nil, fmt.Errorf("unsupported public key PEM block type %q", block.Type)
+	}
+}
+
+// encodePublicKey encodes an RSA public key as a PKIX PEM block
+func encodePublicKey(pubKey *rsa.PublicKey) ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(pubKey)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode public key: %v", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
+
+// keysMatch reports whether pubKey is the public half of privKey
+func keysMatch(pubKey *rsa.PublicKey, privKey *rsa.PrivateKey) bool {
+	return pubKey.E == privKey.PublicKey.E && pubKey.N.Cmp(privKey.PublicKey.N) == 0
+}
+
+// parseEd25519PrivateKey parses a PEM encoded PKCS#8 Ed25519 private key
+func parseEd25519PrivateKey(data []byte) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("could not parse Ed25519 private key: %w", ErrNoPEMBlock)
+	}
+
+	if block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("unsupported Ed25519 private key PEM block type %q", block.Type)
+	}
+
+	keyBase, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse PKCS#8 private key: %v", err)
+	}
+
+	privKey, ok := keyBase.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("could not use PKCS#8 private key: %w", ErrNotEd25519Key)
+	}
+
+	return privKey, nil
+}
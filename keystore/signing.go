@@ -0,0 +1,51 @@
+package keystore
+
+import "crypto"
+
+const (
+	// AlgorithmEd25519 is the RFC 9421 algorithm name for Ed25519
+	AlgorithmEd25519 = "ed25519"
+	// AlgorithmRSAv15SHA256 is the RFC 9421 algorithm name for RSASSA-PKCS1-v1_5 with SHA-256
+	AlgorithmRSAv15SHA256 = "rsa-v1_5-sha256"
+	// AlgorithmRSASHA256 is the draft-cavage algorithm name for RSASSA-PKCS1-v1_5 with SHA-256
+	AlgorithmRSASHA256 = "rsa-sha256"
+)
+
+// SigningKey is a key used to sign outgoing HTTP messages
+type SigningKey struct {
+	// ID is the ID of the key in the Store
+	ID        string
+	Algorithm string
+	Signer    crypto.Signer
+}
+
+// RFC9421Key returns the key used for RFC 9421 signatures. The Ed25519 key
+// is preferred, falling back to the active RSA key
+func (s *Store) RFC9421Key() SigningKey {
+	edKey, ok := s.Ed25519Key()
+	if ok {
+		return SigningKey{
+			ID:        edKey.ID,
+			Algorithm: AlgorithmEd25519,
+			Signer:    edKey.PrivKey,
+		}
+	}
+
+	key := s.ActiveKey()
+	return SigningKey{
+		ID:        key.ID,
+		Algorithm: AlgorithmRSAv15SHA256,
+		Signer:    key.PrivKey,
+	}
+}
+
+// CavageKey returns the key used for draft-cavage signatures, which is
+// always the active RSA key since most implementations only support RSA
+func (s *Store) CavageKey() SigningKey {
+	key := s.ActiveKey()
+	return SigningKey{
+		ID:        key.ID,
+		Algorithm: AlgorithmRSASHA256,
+		Signer:    key.PrivKey,
+	}
+}
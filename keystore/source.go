@@ -0,0 +1,85 @@
+package keystore
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+// ErrNoKeySource is returned when a Source has nothing to load from
+var ErrNoKeySource = errors.New("no key source given")
+
+// Source describes where PEM encoded key data is loaded from. Exactly one
+// of PEM, Env and Path should be set
+type Source struct {
+	// PEM is inline PEM encoded key data
+	PEM string
+	// Env is the name of an environment variable holding PEM encoded key data
+	Env string
+	// Path is the path of a file holding PEM encoded key data, such as a
+	// mounted secret
+	Path string
+}
+
+// IsZero reports whether no source is set
+func (s Source) IsZero() bool {
+	return s.PEM == "" && s.Env == "" && s.Path == ""
+}
+
+// Validate checks that exactly one source is set
+func (s Source) Validate() error {
+	set := 0
+	for _, field := range []string{s.PEM, s.Env, s.Path} {
+		if field != "" {
+			set++
+		}
+	}
+
+	switch set {
+	case 0:
+		return ErrNoKeySource
+	case 1:
+		return nil
+	default:
+		return fmt.Errorf("only one of inline PEM, environment variable or file path may be given")
+	}
+}
+
+// Load returns the key data from the source
+func (s Source) Load() ([]byte, error) {
+	err := s.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case s.PEM != "":
+		return []byte(s.PEM), nil
+	case s.Env != "":
+		data, ok := os.LookupEnv(s.Env)
+		if !ok || data == "" {
+			return nil, fmt.Errorf("environment variable %s is not set", s.Env)
+		}
+		return []byte(data), nil
+	default:
+		data, err := ioutil.ReadFile(s.Path)
+		if err != nil {
+			return nil, fmt.Errorf("could not read key file %s: %v", s.Path, err)
+		}
+		return data, nil
+	}
+}
+
+func (s Source) String() string {
+	switch {
+	case s.PEM != "":
+		return "inline PEM"
+	case s.Env != "":
+		return "environment variable " + s.Env
+	case s.Path != "":
+		return "file " + s.Path
+	default:
+		return "no source"
+	}
+}
@@ -0,0 +1,101 @@
+package keystore
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSourceLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keystore")
+	if err != nil {
+		t.Fatalf("could not create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	keyPath := filepath.Join(dir, "privkey.pem")
+	err = ioutil.WriteFile(keyPath, []byte(MockPrivKey), 0600)
+	if err != nil {
+		t.Fatalf("could not write private key: %v", err)
+	}
+
+	os.Setenv("TURNOVER_TEST_PRIVATE_KEY", MockPrivKey)
+	defer os.Unsetenv("TURNOVER_TEST_PRIVATE_KEY")
+
+	for _, src := range []Source{
+		{PEM: MockPrivKey},
+		{Env: "TURNOVER_TEST_PRIVATE_KEY"},
+		{Path: keyPath},
+	} {
+		store, err := NewStore(src, Source{})
+		if err != nil {
+			t.Errorf("could not create store from %s: %v", src, err)
+			continue
+		}
+
+		if string(store.PubKeyPem()) == "" {
+			t.Errorf("expected public key to be derived from %s", src)
+		}
+	}
+
+	for _, src := range []Source{
+		{},
+		{PEM: MockPrivKey, Path: keyPath},
+		{Env: "TURNOVER_TEST_UNSET_PRIVATE_KEY"},
+	} {
+		_, err := NewStore(src, Source{})
+		if err == nil {
+			t.Errorf("expected error creating store from %s", src)
+		}
+	}
+}
+
+func TestStoreReload(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "keystore")
+	if err != nil {
+		t.Fatalf("could not create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	keyPath := filepath.Join(dir, "privkey.pem")
+	err = ioutil.WriteFile(keyPath, []byte(MockPrivKey), 0600)
+	if err != nil {
+		t.Fatalf("could not write private key: %v", err)
+	}
+
+	store, err := NewStore(Source{Path: keyPath}, Source{})
+	if err != nil {
+		t.Fatalf("could not create store: %v", err)
+	}
+
+	rotated, err := store.Reload()
+	if err != nil || rotated {
+		t.Errorf("expected reload of unchanged key not to rotate got %v (%v)", rotated, err)
+	}
+
+	err = ioutil.WriteFile(keyPath, mockPKCS8OtherKey(t), 0600)
+	if err != nil {
+		t.Fatalf("could not write private key: %v", err)
+	}
+
+	rotated, err = store.Reload()
+	if err != nil || !rotated {
+		t.Errorf("expected reload of changed key to rotate got %v (%v)", rotated, err)
+	}
+
+	if len(store.Keys()) != 2 {
+		t.Errorf("expected previous key to still be advertised got %d keys", len(store.Keys()))
+	}
+
+	// a restart loads the rotated key under the ID it was advertised with
+	restarted, err := NewStore(Source{Path: keyPath}, Source{})
+	if err != nil {
+		t.Fatalf("could not create store after restart: %v", err)
+	}
+	if restarted.ActiveKey().ID != store.ActiveKey().ID {
+		t.Errorf("expected key ID %s after restart got %s", store.ActiveKey().ID, restarted.ActiveKey().ID)
+	}
+}
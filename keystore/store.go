@@ -1,11 +1,14 @@
 package keystore
 
 import (
+	"crypto/ed25519"
 	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/x509"
-	"encoding/pem"
+	"encoding/hex"
 	"fmt"
-	"io/ioutil"
+	"sync"
+	"time"
 )
 
 // MockPrivKey is a mock private key string used for tests
@@ -50,43 +53,308 @@ Z+7NHpTdO3spA88WSwsxtHFY6aUd+KMidRGOqqg3m8jy5mN6HDC+aG3KBYJkQH6C
 PQIDAQAB
 -----END PUBLIC KEY-----`
 
-// Store holds public and private keys for use with the relay
+// Key is an RSA keypair held by a Store
+type Key struct {
+	// ID identifies the key and is used as the fragment of its key IRI. It
+	// is derived from the public key, so a key keeps its ID across restarts
+	ID        string
+	PubKey    *rsa.PublicKey
+	PrivKey   *rsa.PrivateKey
+	PubKeyPem []byte
+	Created   time.Time
+	// Retired is the time the key stopped being the active signing key,
+	// and is zero for the active key
+	Retired time.Time
+}
+
+// Ed25519KeyID is the ID of the Ed25519 key of a Store
+const Ed25519KeyID = "ed25519-key"
+
+// Ed25519Key is an Ed25519 keypair held by a Store
+type Ed25519Key struct {
+	ID      string
+	PubKey  ed25519.PublicKey
+	PrivKey ed25519.PrivateKey
+}
+
+// Store holds public and private keys for use with the relay. The first
+// RSA key is the active signing key, any other RSA keys have been rotated
+// out but are still advertised until they are retired. A Store may also
+// hold an Ed25519 key which is published as a Multikey
 type Store struct {
-	privKeyPath, pubKeyPath string
-	pubKeyPem               []byte
-	pubKey                  *rsa.PublicKey
-	privKey                 *rsa.PrivateKey
+	// privSource is where the active RSA key was loaded from
+	privSource Source
+
+	mu            sync.RWMutex
+	keys          []Key
+	ed25519Key    *Ed25519Key
+	ed25519Source Source
+	listeners     []func()
 }
 
-// PubKey returns the public key of the Store
+// PubKey returns the public key of the active key
 func (s *Store) PubKey() *rsa.PublicKey {
-	return s.pubKey
+	return s.ActiveKey().PubKey
 }
 
-// PrivKey returns the private key of the Store
+// PrivKey returns the private key of the active key
 func (s *Store) PrivKey() *rsa.PrivateKey {
-	return s.privKey
+	return s.ActiveKey().PrivKey
 }
 
-// PubKeyPem returns the PEM encoded public key of the Store
+// PubKeyPem returns the PEM encoded public key of the active key
 func (s *Store) PubKeyPem() []byte {
-	return s.pubKeyPem
+	return s.ActiveKey().PubKeyPem
+}
+
+// ActiveKey returns the key currently used for signing
+func (s *Store) ActiveKey() Key {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.keys[0]
+}
+
+// Keys returns all keys that should be advertised, active key first
+func (s *Store) Keys() []Key {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	keys := make([]Key, len(s.keys))
+	copy(keys, s.keys)
+	return keys
+}
+
+// Key returns the advertised key with the given ID
+func (s *Store) Key(id string) (Key, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, key := range s.keys {
+		if key.ID == id {
+			return key, true
+		}
+	}
+	return Key{}, false
+}
+
+// Ed25519Key returns the Ed25519 key of the Store, if it has one
+func (s *Store) Ed25519Key() (Ed25519Key, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.ed25519Key == nil {
+		return Ed25519Key{}, false
+	}
+	return *s.ed25519Key, true
+}
+
+// SetEd25519Key sets the Ed25519 key held alongside the RSA keys
+func (s *Store) SetEd25519Key(privKey ed25519.PrivateKey) {
+	s.mu.Lock()
+	s.ed25519Key = &Ed25519Key{
+		ID:      Ed25519KeyID,
+		PubKey:  privKey.Public().(ed25519.PublicKey),
+		PrivKey: privKey,
+	}
+	s.mu.Unlock()
+
+	s.notify()
+}
+
+// LoadEd25519Key reads a PKCS#8 Ed25519 private key from src and sets it
+// as the Ed25519 key of the Store
+func (s *Store) LoadEd25519Key(src Source) error {
+	privKeyBytes, err := src.Load()
+	if err != nil {
+		return fmt.Errorf("could not load Ed25519 private key from %s: %v", src, err)
+	}
+
+	privKey, err := parseEd25519PrivateKey(privKeyBytes)
+	if err != nil {
+		return err
+	}
+
+	s.SetEd25519Key(privKey)
+	s.mu.Lock()
+	s.ed25519Source = src
+	s.mu.Unlock()
+	return nil
+}
+
+// OnChange registers fn to be called whenever the set of keys changes
+func (s *Store) OnChange(fn func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, fn)
+}
+
+// Rotate makes privKey the active signing key. The previously active key
+// is kept and advertised until it is removed by RetireExpired
+func (s *Store) Rotate(privKey *rsa.PrivateKey) (Key, error) {
+	key, err := newKey(privKey, nil)
+	if err != nil {
+		return Key{}, err
+	}
+
+	s.mu.Lock()
+	for _, existing := range s.keys {
+		if existing.ID == key.ID {
+			s.mu.Unlock()
+			return Key{}, fmt.Errorf("key %s is already in the store", key.ID)
+		}
+	}
+	s.keys[0].Retired = key.Created
+	s.keys = append([]Key{key}, s.keys...)
+	s.mu.Unlock()
+
+	s.notify()
+	return key, nil
+}
+
+// Reload re-reads the private keys from the sources the Store was loaded
+// from. It rotates to the RSA key if it differs from the active key and
+// reports whether it did so. A changed Ed25519 key replaces the current one.
+// Keys given inline or through the environment do not change while running,
+// so reloading is only useful for keys loaded from files
+func (s *Store) Reload() (bool, error) {
+	if s.privSource.IsZero() {
+		return false, fmt.Errorf("store was not loaded from a key source")
+	}
+
+	privKeyBytes, err := s.privSource.Load()
+	if err != nil {
+		return false, fmt.Errorf("could not load private key from %s: %v", s.privSource, err)
+	}
+
+	privKey, err := parsePrivateKey(privKeyBytes)
+	if err != nil {
+		return false, err
+	}
+
+	err = s.reloadEd25519()
+	if err != nil {
+		return false, err
+	}
+
+	if keysMatch(s.PubKey(), privKey) {
+		return false, nil
+	}
+
+	_, err = s.Rotate(privKey)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
 }
 
-// NewStore creates a new Store
-func NewStore(privKeyPath, pubKeyPath string) (*Store, error) {
-	if privKeyPath == "" || pubKeyPath == "" {
-		return nil, fmt.Errorf("private key and public key paths are not specified properly")
+func (s *Store) reloadEd25519() error {
+	s.mu.RLock()
+	src := s.ed25519Source
+	s.mu.RUnlock()
+	if src.IsZero() {
+		return nil
 	}
 
-	pubKeyBytes, err := ioutil.ReadFile(pubKeyPath)
+	privKeyBytes, err := src.Load()
 	if err != nil {
-		return nil, fmt.Errorf("could not read public key file")
+		return fmt.Errorf("could not load Ed25519 private key from %s: %v", src, err)
 	}
 
-	privKeyBytes, err := ioutil.ReadFile(privKeyPath)
+	privKey, err := parseEd25519PrivateKey(privKeyBytes)
 	if err != nil {
-		return nil, fmt.Errorf("could not read private key file")
+		return err
+	}
+
+	current, ok := s.Ed25519Key()
+	if ok && current.PrivKey.Equal(privKey) {
+		return nil
+	}
+
+	s.SetEd25519Key(privKey)
+	return nil
+}
+
+// RetireExpired removes keys which were rotated out more than grace before now
+// and returns the removed keys
+func (s *Store) RetireExpired(grace time.Duration, now time.Time) []Key {
+	s.mu.Lock()
+	kept := s.keys[:1]
+	retired := make([]Key, 0)
+	for _, key := range s.keys[1:] {
+		if now.Sub(key.Retired) > grace {
+			retired = append(retired, key)
+		} else {
+			kept = append(kept, key)
+		}
+	}
+	s.keys = kept
+	s.mu.Unlock()
+
+	if len(retired) > 0 {
+		s.notify()
+	}
+	return retired
+}
+
+func (s *Store) notify() {
+	s.mu.RLock()
+	listeners := make([]func(), len(s.listeners))
+	copy(listeners, s.listeners)
+	s.mu.RUnlock()
+
+	for _, fn := range listeners {
+		fn()
+	}
+}
+
+// newKey builds a Key from privKey, with an ID derived from the public key
+// fingerprint. A nil pubKeyPem is derived from privKey
+func newKey(privKey *rsa.PrivateKey, pubKeyPem []byte) (Key, error) {
+	var err error
+	if pubKeyPem == nil {
+		pubKeyPem, err = encodePublicKey(&privKey.PublicKey)
+		if err != nil {
+			return Key{}, err
+		}
+	}
+
+	id, err := fingerprintID(&privKey.PublicKey)
+	if err != nil {
+		return Key{}, err
+	}
+
+	return Key{
+		ID:        id,
+		PubKey:    &privKey.PublicKey,
+		PrivKey:   privKey,
+		PubKeyPem: pubKeyPem,
+		Created:   time.Now(),
+	}, nil
+}
+
+// fingerprintID derives a key ID from the SHA-256 of the PKIX public key
+func fingerprintID(pubKey *rsa.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pubKey)
+	if err != nil {
+		return "", fmt.Errorf("could not encode public key: %v", err)
+	}
+
+	sum := sha256.Sum256(der)
+	return "key-" + hex.EncodeToString(sum[:8]), nil
+}
+
+// NewStore creates a new Store from a private key source. pubKeySrc may be
+// empty, in which case the public key is derived from the private key
+func NewStore(privKeySrc, pubKeySrc Source) (*Store, error) {
+	privKeyBytes, err := privKeySrc.Load()
+	if err != nil {
+		return nil, fmt.Errorf("could not load private key from %s: %v", privKeySrc, err)
+	}
+
+	var pubKeyBytes []byte
+	if !pubKeySrc.IsZero() {
+		pubKeyBytes, err = pubKeySrc.Load()
+		if err != nil {
+			return nil, fmt.Errorf("could not load public key from %s: %v", pubKeySrc, err)
+		}
 	}
 
 	store, err := makeStore(pubKeyBytes, privKeyBytes)
@@ -94,35 +362,42 @@ func NewStore(privKeyPath, pubKeyPath string) (*Store, error) {
 		return nil, err
 	}
 
-	store.privKeyPath = privKeyPath
-	store.pubKeyPath = pubKeyPath
+	store.privSource = privKeySrc
 	return store, nil
 }
 
+// makeStore builds a Store from PEM encoded key data. If pubKeyBytes is empty
+// the public key is derived from the private key, otherwise the two keys
+// must form a pair
 func makeStore(pubKeyBytes, privKeyBytes []byte) (*Store, error) {
-	pubKeyBytesDec, _ := pem.Decode(pubKeyBytes)
-	pubKeyBase, err := x509.ParsePKIXPublicKey(pubKeyBytesDec.Bytes)
+	privKey, err := parsePrivateKey(privKeyBytes)
 	if err != nil {
-		return nil, fmt.Errorf("could not parse public key: %v", err)
+		return nil, err
 	}
 
-	pubKey, ok := pubKeyBase.(*rsa.PublicKey)
-	if !ok {
-		return nil, fmt.Errorf("public key is not in RSA form")
+	if len(pubKeyBytes) == 0 {
+		pubKeyBytes, err = encodePublicKey(&privKey.PublicKey)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	privKeyBytesDec, _ := pem.Decode(privKeyBytes)
-	privKey, err := x509.ParsePKCS1PrivateKey(privKeyBytesDec.Bytes)
+	pubKey, err := parsePublicKey(pubKeyBytes)
 	if err != nil {
-		return nil, fmt.Errorf("could not parse private key")
+		return nil, err
+	}
+
+	if !keysMatch(pubKey, privKey) {
+		return nil, ErrKeyMismatch
+	}
+
+	key, err := newKey(privKey, pubKeyBytes)
+	if err != nil {
+		return nil, err
 	}
 
 	return &Store{
-		privKeyPath: "",
-		pubKeyPath:  "",
-		privKey:     privKey,
-		pubKey:      pubKey,
-		pubKeyPem:   pubKeyBytes,
+		keys: []Key{key},
 	}, nil
 }
 
@@ -133,7 +408,5 @@ func MockStore() *Store {
 		panic(err)
 	}
 
-	store.privKeyPath = ""
-	store.pubKeyPath = ""
 	return store
 }
@@ -1,7 +1,15 @@
 package keystore
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestKeyStore(t *testing.T) {
@@ -16,3 +24,178 @@ func TestKeyStore(t *testing.T) {
 		)
 	}
 }
+
+func mockPKCS8PrivKey(t *testing.T) []byte {
+	privKey, err := parsePrivateKey([]byte(MockPrivKey))
+	if err != nil {
+		t.Fatalf("could not parse mock private key: %v", err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(privKey)
+	if err != nil {
+		t.Fatalf("could not marshal mock private key as PKCS#8: %v", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+func TestMakeStorePKCS8(t *testing.T) {
+	t.Parallel()
+
+	store, err := makeStore([]byte(MockPubKey), mockPKCS8PrivKey(t))
+	if err != nil {
+		t.Fatalf("could not make store from PKCS#8 private key: %v", err)
+	}
+
+	if store.PrivKey() == nil {
+		t.Errorf("expected private key to be set")
+	}
+}
+
+func TestMakeStoreDerivesPubKey(t *testing.T) {
+	t.Parallel()
+
+	store, err := makeStore(nil, []byte(MockPrivKey))
+	if err != nil {
+		t.Fatalf("could not make store without public key: %v", err)
+	}
+
+	mockPubKey, err := parsePublicKey([]byte(MockPubKey))
+	if err != nil {
+		t.Fatalf("could not parse mock public key: %v", err)
+	}
+
+	if store.PubKey().N.Cmp(mockPubKey.N) != 0 || store.PubKey().E != mockPubKey.E {
+		t.Errorf("derived public key does not match mock public key")
+	}
+
+	derived, err := parsePublicKey(store.PubKeyPem())
+	if err != nil {
+		t.Fatalf("derived public key PEM does not parse: %v", err)
+	}
+
+	if derived.N.Cmp(mockPubKey.N) != 0 {
+		t.Errorf("derived public key PEM does not match mock public key")
+	}
+}
+
+func TestMakeStoreErrors(t *testing.T) {
+	t.Parallel()
+
+	otherPubKey, err := encodePublicKey(&mockOtherKey(t).PublicKey)
+	if err != nil {
+		t.Fatalf("could not encode other public key: %v", err)
+	}
+
+	var tests = []struct {
+		name    string
+		pubKey  []byte
+		privKey []byte
+	}{
+		{"malformed private key", []byte(MockPubKey), []byte("not a key")},
+		{"malformed public key", []byte("not a key"), []byte(MockPrivKey)},
+		{"public key in private key slot", []byte(MockPubKey), []byte(MockPubKey)},
+		{"mismatched key pair", otherPubKey, []byte(MockPrivKey)},
+	}
+
+	for _, tt := range tests {
+		var tt = tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := makeStore(tt.pubKey, tt.privKey)
+			if err == nil {
+				t.Errorf("expected error making store")
+			}
+		})
+	}
+}
+
+func TestNewStoreFromFiles(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "keystore")
+	if err != nil {
+		t.Fatalf("could not create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	privPath := filepath.Join(dir, "privkey.pem")
+	err = ioutil.WriteFile(privPath, mockPKCS8PrivKey(t), 0600)
+	if err != nil {
+		t.Fatalf("could not write private key: %v", err)
+	}
+
+	store, err := NewStore(Source{Path: privPath}, Source{})
+	if err != nil {
+		t.Fatalf("could not create store from private key file: %v", err)
+	}
+
+	if store.PubKey() == nil {
+		t.Errorf("expected derived public key")
+	}
+
+	_, err = NewStore(Source{Path: filepath.Join(dir, "missing.pem")}, Source{})
+	if err == nil {
+		t.Errorf("expected error for missing private key file")
+	}
+}
+
+func mockOtherKey(t *testing.T) *rsa.PrivateKey {
+	privKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("could not generate RSA key: %v", err)
+	}
+
+	return privKey
+}
+
+func TestStoreRotate(t *testing.T) {
+	t.Parallel()
+
+	store := MockStore()
+	mainKeyID := store.ActiveKey().ID
+	changes := 0
+	store.OnChange(func() { changes++ })
+
+	key, err := store.Rotate(mockOtherKey(t))
+	if err != nil {
+		t.Fatalf("could not rotate key: %v", err)
+	}
+
+	if store.ActiveKey().ID != key.ID || key.ID == mainKeyID {
+		t.Errorf("expected rotated key %s to be active got %s", key.ID, store.ActiveKey().ID)
+	}
+
+	previous, ok := store.Key(mainKeyID)
+	if !ok || previous.Retired.IsZero() {
+		t.Errorf("expected previous key to be kept and marked retired")
+	}
+
+	retired := store.RetireExpired(time.Hour, previous.Retired.Add(time.Minute))
+	if len(retired) != 0 {
+		t.Errorf("expected no keys retired within grace period got %d", len(retired))
+	}
+
+	retired = store.RetireExpired(time.Hour, previous.Retired.Add(2*time.Hour))
+	if len(retired) != 1 || retired[0].ID != mainKeyID {
+		t.Errorf("expected previous key to be retired got %v", retired)
+	}
+
+	if len(store.Keys()) != 1 {
+		t.Errorf("expected only active key to remain got %d keys", len(store.Keys()))
+	}
+
+	if changes != 2 {
+		t.Errorf("expected 2 change notifications got %d", changes)
+	}
+}
+
+func mockPKCS8OtherKey(t *testing.T) []byte {
+	der, err := x509.MarshalPKCS8PrivateKey(mockOtherKey(t))
+	if err != nil {
+		t.Fatalf("could not marshal private key as PKCS#8: %v", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
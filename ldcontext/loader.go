@@ -0,0 +1,189 @@
+// Package ldcontext serves the JSON-LD contexts used by ActivityPub software
+// from copies bundled into the binary, so expanding an activity does not
+// fetch anything over the network
+package ldcontext
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/piprate/json-gold/ld"
+)
+
+// bundled holds the contexts. The data integrity and multikey contexts are
+// rewritten without the JSON-LD 1.1 keywords of the originals, which
+// json-gold cannot process
+//
+//go:embed contexts/*.jsonld
+var bundled embed.FS
+
+// litepubSuffix is the path Pleroma and Akkoma serve the LitePub context
+// under. Every instance uses its own copy, so the context is matched by path
+const litepubSuffix = "/schemas/litepub-0.1.jsonld"
+
+// bundledURLs maps context URLs to the bundled documents serving them
+var bundledURLs = map[string]string{
+	"https://www.w3.org/ns/activitystreams":                "activitystreams.jsonld",
+	"http://www.w3.org/ns/activitystreams":                 "activitystreams.jsonld",
+	"https://www.w3.org/ns/activitystreams.jsonld":         "activitystreams.jsonld",
+	"https://w3id.org/security/v1":                         "security-v1.jsonld",
+	"https://web-payments.org/contexts/security-v1.jsonld": "security-v1.jsonld",
+	"https://w3id.org/security/data-integrity/v1":          "data-integrity-v1.jsonld",
+	"https://w3id.org/security/multikey/v1":                "multikey-v1.jsonld",
+	"https://litepub.social/litepub/context.jsonld":        "litepub-0.1.jsonld",
+	"http://joinmastodon.org/ns":                           "toot.jsonld",
+	"http://schema.org":                                    "schema.jsonld",
+	"https://schema.org":                                   "schema.jsonld",
+}
+
+// ErrContextNotAllowed is returned when a context is neither bundled nor
+// on the allowlist of contexts which may be fetched
+var ErrContextNotAllowed = errors.New("context is not bundled or allowlisted")
+
+// Loader is a json-gold DocumentLoader serving bundled contexts. Other
+// contexts fail to load unless network fetching was enabled with
+// WithFallback and they are on its allowlist
+type Loader struct {
+	docs map[string]interface{}
+
+	mu       sync.RWMutex
+	fetched  map[string]*ld.RemoteDocument
+	fallback ld.DocumentLoader
+	allowed  []string
+}
+
+// NewLoader creates a Loader serving the bundled contexts
+func NewLoader() *Loader {
+	return &Loader{
+		docs:    parseBundled(),
+		fetched: make(map[string]*ld.RemoteDocument),
+	}
+}
+
+// parseBundled parses the bundled contexts, keyed by file name
+func parseBundled() map[string]interface{} {
+	docs := make(map[string]interface{})
+	for _, name := range bundledURLs {
+		if _, ok := docs[name]; ok {
+			continue
+		}
+
+		b, err := bundled.ReadFile("contexts/" + name)
+		if err != nil {
+			panic(fmt.Sprintf("missing bundled context %s: %v", name, err))
+		}
+
+		doc, err := ld.DocumentFromReader(bytes.NewReader(b))
+		if err != nil {
+			panic(fmt.Sprintf("invalid bundled context %s: %v", name, err))
+		}
+		docs[name] = doc
+	}
+
+	for name, doc := range docs {
+		docs[name] = inlineContexts(doc, docs)
+	}
+	return docs
+}
+
+// WithFallback lets the Loader fetch contexts which are not bundled using
+// client, as long as their URL starts with one of the allowed prefixes.
+// Fetched contexts are cached for the lifetime of the Loader
+func (l *Loader) WithFallback(client *http.Client, allowed []string) *Loader {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.fallback = ld.NewDefaultDocumentLoader(client)
+	l.allowed = allowed
+	return l
+}
+
+// LoadDocument returns the context at u
+func (l *Loader) LoadDocument(u string) (*ld.RemoteDocument, error) {
+	contextURL := strings.TrimSuffix(u, "#")
+
+	name, ok := bundledURLs[contextURL]
+	if !ok && strings.HasSuffix(contextURL, litepubSuffix) {
+		name, ok = "litepub-0.1.jsonld", true
+	}
+	if ok {
+		return &ld.RemoteDocument{DocumentURL: u, Document: l.docs[name]}, nil
+	}
+
+	l.mu.RLock()
+	doc, cached := l.fetched[contextURL]
+	fallback := l.fallback
+	allowed := l.isAllowed(contextURL)
+	l.mu.RUnlock()
+
+	if cached {
+		return doc, nil
+	}
+
+	if fallback == nil || !allowed {
+		return nil, ld.NewJsonLdError(
+			ld.LoadingRemoteContextFailed,
+			fmt.Errorf("%w: %s", ErrContextNotAllowed, u),
+		)
+	}
+
+	doc, err := fallback.LoadDocument(contextURL)
+	if err != nil {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	l.fetched[contextURL] = doc
+	l.mu.Unlock()
+
+	return doc, nil
+}
+
+// inlineContexts replaces references to other bundled contexts inside the
+// @context of doc with their definitions. json-gold treats a context which
+// is referenced twice while expanding a document as recursive, which breaks
+// documents listing both ActivityStreams and LitePub
+func inlineContexts(doc interface{}, docs map[string]interface{}) interface{} {
+	node, ok := doc.(map[string]interface{})
+	if !ok {
+		return doc
+	}
+
+	contexts, ok := node["@context"].([]interface{})
+	if !ok {
+		return doc
+	}
+
+	inlined := make([]interface{}, 0, len(contexts))
+	for _, context := range contexts {
+		contextURL, ok := context.(string)
+		if !ok {
+			inlined = append(inlined, context)
+			continue
+		}
+
+		ref, ok := docs[bundledURLs[contextURL]].(map[string]interface{})
+		if !ok {
+			inlined = append(inlined, context)
+			continue
+		}
+		inlined = append(inlined, ref["@context"])
+	}
+
+	return map[string]interface{}{"@context": inlined}
+}
+
+// isAllowed reports whether contextURL may be fetched. Callers must hold mu
+func (l *Loader) isAllowed(contextURL string) bool {
+	for _, prefix := range l.allowed {
+		if prefix != "" && strings.HasPrefix(contextURL, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+package ldcontext
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/piprate/json-gold/ld"
+)
+
+func expand(t *testing.T, loader ld.DocumentLoader, doc map[string]interface{}) ([]interface{}, error) {
+	t.Helper()
+
+	opts := ld.NewJsonLdOptions("")
+	opts.DocumentLoader = loader
+	return ld.NewJsonLdProcessor().Expand(doc, opts)
+}
+
+func TestLoaderBundledContexts(t *testing.T) {
+	t.Parallel()
+
+	loader := NewLoader()
+	for contextURL := range bundledURLs {
+		var contextURL = contextURL
+		t.Run(contextURL, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := expand(t, loader, map[string]interface{}{
+				"@context": contextURL,
+				"@id":      "https://example.com/1",
+			})
+			if err != nil {
+				t.Errorf("could not expand with bundled context: %v", err)
+			}
+		})
+	}
+}
+
+func TestLoaderExpandsActor(t *testing.T) {
+	t.Parallel()
+
+	expanded, err := expand(t, NewLoader(), map[string]interface{}{
+		"@context": []interface{}{
+			"https://www.w3.org/ns/activitystreams",
+			"https://w3id.org/security/v1",
+			"https://w3id.org/security/data-integrity/v1",
+			"https://pleroma.example.com/schemas/litepub-0.1.jsonld",
+		},
+		"id":   "https://example.com/actor",
+		"type": "Person",
+		"publicKey": map[string]interface{}{
+			"id":           "https://example.com/actor#main-key",
+			"publicKeyPem": "pem",
+		},
+		"discoverable": true,
+	})
+	if err != nil {
+		t.Fatalf("could not expand actor: %v", err)
+	}
+
+	node := expanded[0].(map[string]interface{})
+	for _, iri := range []string{
+		"https://w3id.org/security#publicKey",
+		"http://joinmastodon.org/ns#discoverable",
+	} {
+		if _, ok := node[iri]; !ok {
+			t.Errorf("expected %s in expanded actor %v", iri, node)
+		}
+	}
+}
+
+func TestLoaderRejectsUnknownContexts(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewLoader().LoadDocument("https://example.com/context.jsonld")
+	if err == nil {
+		t.Fatal("expected unknown context to be rejected")
+	}
+
+	ldErr, ok := err.(*ld.JsonLdError)
+	if !ok {
+		t.Fatalf("expected a JsonLdError got %T", err)
+	}
+	if details, ok := ldErr.Details.(error); !ok || !errors.Is(details, ErrContextNotAllowed) {
+		t.Errorf("expected ErrContextNotAllowed got %v", ldErr.Details)
+	}
+}
+
+func TestLoaderFallback(t *testing.T) {
+	t.Parallel()
+
+	var fetches int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		w.Header().Set("Content-Type", "application/ld+json")
+		fmt.Fprint(w, `{"@context": {"custom": "https://example.com/ns#custom"}}`)
+	}))
+	defer server.Close()
+
+	loader := NewLoader().WithFallback(server.Client(), []string{server.URL + "/allowed/"})
+
+	for i := 0; i < 2; i++ {
+		_, err := loader.LoadDocument(server.URL + "/allowed/context.jsonld")
+		if err != nil {
+			t.Fatalf("could not load allowlisted context: %v", err)
+		}
+	}
+	if atomic.LoadInt32(&fetches) != 1 {
+		t.Errorf("expected allowlisted context to be fetched once got %d", fetches)
+	}
+
+	_, err := loader.LoadDocument(server.URL + "/other/context.jsonld")
+	if err == nil {
+		t.Error("expected context outside the allowlist to be rejected")
+	}
+	if atomic.LoadInt32(&fetches) != 1 {
+		t.Errorf("expected no fetch for context outside the allowlist got %d", fetches)
+	}
+}
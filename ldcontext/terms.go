@@ -0,0 +1,50 @@
+package ldcontext
+
+import (
+	"strings"
+	"sync"
+)
+
+// termDocs holds the bundled contexts Terms reads, parsed on first use
+var termDocs = sync.OnceValue(parseBundled)
+
+// Terms returns the terms a bundled context defines, mapped to the IRIs
+// they expand to. Keyword aliases such as id and type are left out. It
+// returns nil when contextURL is not a bundled context with a single
+// context object
+func Terms(contextURL string) map[string]string {
+	doc, ok := termDocs()[bundledURLs[contextURL]].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	context, ok := doc["@context"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	terms := make(map[string]string, len(context))
+	for term, definition := range context {
+		var iri string
+		switch def := definition.(type) {
+		case string:
+			iri = def
+		case map[string]interface{}:
+			iri, _ = def["@id"].(string)
+		}
+
+		if iri == "" || strings.HasPrefix(iri, "@") || strings.HasPrefix(term, "@") {
+			continue
+		}
+
+		prefix, suffix, found := strings.Cut(iri, ":")
+		if found && !strings.HasPrefix(suffix, "//") {
+			if expanded, ok := context[prefix].(string); ok {
+				iri = expanded + suffix
+			}
+		}
+		terms[term] = iri
+	}
+
+	return terms
+}
@@ -1,16 +1,41 @@
 package main
 
 import (
+	"expvar"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/Koshroy/turnover/activities"
 	"github.com/Koshroy/turnover/controllers"
+	"github.com/Koshroy/turnover/httpsig"
 	"github.com/Koshroy/turnover/keystore"
+	"github.com/Koshroy/turnover/ldcontext"
 	mware "github.com/Koshroy/turnover/middleware"
+	"github.com/Koshroy/turnover/seen"
+	"github.com/Koshroy/turnover/subscribers"
+	"github.com/Koshroy/turnover/tasks"
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
 )
 
+// version is the version of turnover, which can be set at build time with
+// -ldflags "-X main.version=..."
+var version = "0.1.0-dev"
+
+const keyRetireInterval = time.Hour
+const keyCacheTTL = time.Hour
+const contextFetchTimeout = 10 * time.Second
+const keyFetchTimeout = 10 * time.Second
+const deliveryTimeout = 30 * time.Second
+
+// maxOutboxActivities bounds the outbox. Older activities, including the
+// Accepts of long-standing follows, are dropped and answer 404 once evicted
+const maxOutboxActivities = 1000
+
 func main() {
 	config, err := LoadConfig("config.toml")
 	if err != nil {
@@ -18,24 +43,194 @@ func main() {
 		return
 	}
 
-	store, err := keystore.NewStore(config.Server.PrivateKey, config.Server.PublicKey)
+	store, err := keystore.NewStore(
+		config.Server.PrivateKeySource(), config.Server.PublicKeySource(),
+	)
 	if err != nil {
 		log.Printf("could not read keys properly: %v\n", err)
 		return
 	}
 
+	edKeySrc := config.Server.Ed25519PrivateKeySource()
+	if !edKeySrc.IsZero() {
+		err = store.LoadEd25519Key(edKeySrc)
+		if err != nil {
+			log.Printf("could not read keys properly: %v\n", err)
+			return
+		}
+	}
+
+	keyGrace, err := config.Server.KeyGrace()
+	if err != nil {
+		log.Printf("could not parse config properly: %v\n", err)
+		return
+	}
+
+	policies, err := controllers.ParsePolicies(config.Relay.Policies)
+	if err != nil {
+		log.Printf("could not parse config properly: %v\n", err)
+		return
+	}
+
+	dedupWindow, err := config.Relay.DedupDuration()
+	if err != nil {
+		log.Printf("could not parse config properly: %v\n", err)
+		return
+	}
+
+	var seenSet seen.Set = seen.NewMemorySet(dedupWindow)
+	if config.Relay.DedupFile != "" {
+		fileSet, err := seen.OpenFileSet(config.Relay.DedupFile, dedupWindow, time.Now())
+		if err != nil {
+			log.Printf("could not open dedup file: %v\n", err)
+			return
+		}
+		defer fileSet.Close()
+		seenSet = fileSet
+	}
+
+	limits, err := config.Relay.Limits.Limits()
+	if err != nil {
+		log.Printf("could not parse config properly: %v\n", err)
+		return
+	}
+
+	fetchOpts, err := config.Relay.Fetch.Options()
+	if err != nil {
+		log.Printf("could not parse config properly: %v\n", err)
+		return
+	}
+
+	queue := tasks.NewMemoryQueue()
+	storage := tasks.NewMemoryStorage()
+	// subscribers are not persisted, so they have to follow again after a
+	// restart
+	registry := subscribers.NewMemoryRegistry()
+	worker := tasks.NewWorker(queue, storage)
+	for n := 0; n < config.Relay.WorkerCount(); n++ {
+		go worker.Run()
+	}
+
 	r := chi.NewRouter()
 	r.Use(middleware.RealIP)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
-	r.Use(mware.ActivityPubHeaders)
 
-	actorController := controllers.NewActor(config.Server.Scheme, config.Server.Hostname, store)
+	loader := ldcontext.NewLoader()
+	if len(config.Relay.RemoteContexts) > 0 {
+		loader.WithFallback(httpsig.NewPublicClient(contextFetchTimeout), config.Relay.RemoteContexts)
+	}
+
+	actorController := controllers.NewActor(config.Server.Scheme, config.Server.Hostname, store, config.Actor.Profile())
+	inboxController := controllers.NewInbox(
+		config.Relay.Whitelist,
+		config.Server.Scheme,
+		config.Server.Hostname,
+		loader,
+		queue,
+		storage,
+		registry,
+	)
+
+	inboxController.WithPolicies(policies)
+	inboxController.WithSeenSet(seenSet)
+	inboxController.WithLimits(limits)
+
+	inboxController.WithVerifier(httpsig.NewVerifier(
+		config.Server.Scheme,
+		httpsig.NewKeyFetcher(httpsig.HTTPDocumentFetcher(httpsig.NewPublicClient(keyFetchTimeout)), keyCacheTTL),
+	))
 
-	r.Get("/actor", actorController.ServeHTTP)
+	signer := httpsig.NewSigner(store, actorController.KeyID)
+	schemes := httpsig.NewHostSchemes()
+	deliverer := controllers.NewDeliverer(
+		queue, storage, httpsig.NewPublicClient(deliveryTimeout), signer, schemes,
+	)
+
+	resolver := controllers.NewObjectResolver(
+		httpsig.SignedDocumentFetcher(httpsig.NewPublicClient(fetchOpts.Timeout), signer, schemes, fetchOpts.MaxSize),
+		fetchOpts.CacheTTL,
+		fetchOpts.CacheSize,
+	)
+	inboxController.WithObjectFetcher(resolver.Resolve)
+	outbox := activities.NewMemoryStore(maxOutboxActivities)
+	publisher := controllers.NewPublisher(actorController, outbox, deliverer)
+	inboxController.WithPublisher(publisher)
+	inboxController.WithDeliverer(deliverer)
+	rotator := controllers.NewKeyRotator(actorController, registry, publisher, keyGrace)
+	go manageKeys(rotator)
+
+	webFingerController := controllers.NewWebFinger(actorController)
+	r.Get("/.well-known/webfinger", webFingerController.ServeHTTP)
+
+	hostMetaController := controllers.NewHostMeta(config.Server.Scheme, config.Server.Hostname)
+	r.Get("/.well-known/host-meta", hostMetaController.ServeHTTP)
+	r.Get("/.well-known/host-meta.json", hostMetaController.ServeJSON)
+
+	nodeInfoController := controllers.NewNodeInfo(
+		config.Server.Scheme,
+		config.Server.Hostname,
+		version,
+		config.Relay.Whitelist,
+		registry,
+	)
+	r.Get("/.well-known/nodeinfo", nodeInfoController.ServeDiscovery)
+	r.Get("/nodeinfo/{version}", nodeInfoController.ServeHTTP)
+
+	actorPage := controllers.NewActorPage(actorController, config.Relay.Whitelist, registry)
+	r.With(mware.ActivityPubOrHTML(actorPage)).Get("/actor", actorController.ServeHTTP)
+	r.With(mware.ActivityPubOrHTML(actorPage)).Head("/actor", actorController.ServeHTTP)
+
+	r.Group(func(r chi.Router) {
+		r.Use(mware.ActivityPubHeaders)
+		r.Post("/inbox", inboxController.ServeHTTP)
+		r.Get("/outbox", controllers.NewOutbox(
+			config.Server.Scheme,
+			config.Server.Hostname,
+			outbox,
+			config.Relay.CollectionPageSize,
+		).ServeHTTP)
+		r.Get("/activities/{id}", controllers.NewActivities(actorController, outbox).ServeHTTP)
+		r.Get("/followers", controllers.NewCollection(
+			config.Server.Scheme,
+			config.Server.Hostname,
+			"/followers",
+			registry,
+			config.Relay.CollectionPageSize,
+			config.Relay.HideCollections,
+		).ServeHTTP)
+	})
+
+	if config.Server.Metrics {
+		r.Get("/debug/vars", expvar.Handler().ServeHTTP)
+	}
 
 	err = http.ListenAndServe(":3000", r)
 	if err != nil {
 		panic(err)
 	}
 }
+
+// manageKeys reloads keys from their sources when SIGHUP is received,
+// rotating to a changed private key, and periodically retires old keys
+func manageKeys(rotator *controllers.KeyRotator) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	ticker := time.NewTicker(keyRetireInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-hup:
+			err := rotator.Reload()
+			if err != nil {
+				log.Printf("could not reload keys: %v\n", err)
+			}
+		case now := <-ticker.C:
+			err := rotator.RetireExpired(now)
+			if err != nil {
+				log.Printf("could not retire keys: %v\n", err)
+			}
+		}
+	}
+}
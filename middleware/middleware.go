@@ -1,41 +1,142 @@
 package middleware
 
 import (
+	"mime"
 	"net/http"
+	"strconv"
 	"strings"
 )
 
+const activityStreamsProfile = "https://www.w3.org/ns/activitystreams"
+
+// ActivityPubContentType is the media type set on ActivityPub responses
+const ActivityPubContentType = "application/activity+json"
+
 // ActivityPubHeaders is a middleware which fails the request if ActivityPub headers are
-// not present in the request
+// not present in the request. Requests without a body (GET, HEAD) must accept an
+// ActivityPub media type, and all other requests must send one as their
+// Content-Type. ActivityPub media types are application/activity+json and
+// application/ld+json with the ActivityStreams profile. The response
+// Content-Type defaults to application/activity+json
 func ActivityPubHeaders(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		headerOk := false
+		var headerOk bool
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+			headerOk = acceptsActivityPub(r)
+		default:
+			headerOk = sendsActivityPub(r)
+		}
 
-		acceptTypes, ok := r.Header["Accept"]
-		if ok {
-			for _, acceptType := range acceptTypes {
-				if strings.Contains(acceptType, "application/ld+json") {
-					headerOk = true
-				}
+		if !headerOk {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+
+		w.Header().Set("Content-Type", ActivityPubContentType)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isActivityPubType reports whether a parsed media type is an ActivityPub
+// media type
+func isActivityPubType(mediaType string, params map[string]string) bool {
+	switch mediaType {
+	case "application/activity+json":
+		return true
+	case "application/ld+json":
+		for _, profile := range strings.Fields(params["profile"]) {
+			if profile == activityStreamsProfile {
+				return true
 			}
 		}
+		return false
+	default:
+		return false
+	}
+}
 
-		if !headerOk {
-			contentTypes, ok := r.Header["Content-Type"]
-			if ok {
-				for _, contentType := range contentTypes {
-					if strings.Contains(contentType, "application/activity+json") {
-						headerOk = true
-					}
+// acceptedTypes parses the Accept headers of r, skipping media types
+// which are not acceptable (q=0) or cannot be parsed
+func acceptedTypes(r *http.Request) []acceptedType {
+	types := make([]acceptedType, 0)
+	for _, accepted := range parseAccept(r) {
+		if accepted.weight > 0 {
+			types = append(types, accepted)
+		}
+	}
+	return types
+}
+
+// parseAccept parses the Accept headers of r, including media types which
+// are not acceptable (q=0) and skipping those which cannot be parsed
+func parseAccept(r *http.Request) []acceptedType {
+	types := make([]acceptedType, 0)
+	for _, header := range r.Header["Accept"] {
+		for _, value := range splitAccept(header) {
+			mediaType, params, err := mime.ParseMediaType(value)
+			if err != nil {
+				continue
+			}
+
+			weight := 1.0
+			if q, ok := params["q"]; ok {
+				weight, err = strconv.ParseFloat(q, 64)
+				if err != nil || weight < 0 {
+					continue
 				}
 			}
+
+			types = append(types, acceptedType{mediaType: mediaType, params: params, weight: weight})
 		}
+	}
+	return types
+}
 
-		if !headerOk {
-			w.WriteHeader(http.StatusUnsupportedMediaType)
-			return
+type acceptedType struct {
+	mediaType string
+	params    map[string]string
+	weight    float64
+}
+
+// splitAccept splits an Accept header on commas which are not inside
+// quoted parameter values
+func splitAccept(header string) []string {
+	values := make([]string, 0, 1)
+	inQuotes := false
+	start := 0
+	for i := 0; i < len(header); i++ {
+		switch header[i] {
+		case '"':
+			inQuotes = !inQuotes
+		case '\\':
+			if inQuotes {
+				i++
+			}
+		case ',':
+			if !inQuotes {
+				values = append(values, strings.TrimSpace(header[start:i]))
+				start = i + 1
+			}
 		}
+	}
+	values = append(values, strings.TrimSpace(header[start:]))
+	return values
+}
 
-		next.ServeHTTP(w, r)
-	})
+func acceptsActivityPub(r *http.Request) bool {
+	for _, accepted := range acceptedTypes(r) {
+		if isActivityPubType(accepted.mediaType, accepted.params) {
+			return true
+		}
+	}
+	return false
+}
+
+func sendsActivityPub(r *http.Request) bool {
+	mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+	return isActivityPubType(mediaType, params)
 }
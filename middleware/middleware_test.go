@@ -11,54 +11,113 @@ import (
 func TestActivityPubHeaders(t *testing.T) {
 	t.Parallel()
 
+	const ldProfile = "application/ld+json; profile=\"https://www.w3.org/ns/activitystreams\""
+
 	var tests = []struct {
 		name             string
+		method           string
 		inputContentType []string
 		inputAccept      []string
 		want             int
 	}{
 		{
 			"should accept requests with activitypub accept headers",
+			"GET",
 			[]string{"application/json"},
-			[]string{"application/ld+json; profile=\"https://www.w3.org/ns/activitystreams\""},
+			[]string{ldProfile},
 			http.StatusOK,
 		},
 		{
-			"should accept requests with activitypub accept headers with charset in content type",
-			[]string{"application/json; charset=utf-8"},
-			[]string{"application/ld+json; profile=\"https://www.w3.org/ns/activitystreams\""},
+			"should accept requests with activity+json accept headers",
+			"GET",
+			nil,
+			[]string{"application/activity+json"},
 			http.StatusOK,
 		},
 		{
-			"should accept requests with activitypub accept headers but weird content type",
-			[]string{"text/html"},
-			[]string{"application/ld+json; profile=\"https://www.w3.org/ns/activitystreams\""},
+			"should accept activitypub among several accept types",
+			"GET",
+			nil,
+			[]string{"text/html, application/activity+json;q=0.9"},
 			http.StatusOK,
 		},
 		{
-			"should not accept requests with no accept types and wrong content type",
-			[]string{"application/json"},
+			"should accept profile lists containing activitystreams",
+			"HEAD",
+			nil,
+			[]string{"application/ld+json; profile=\"https://example.com/profile https://www.w3.org/ns/activitystreams\""},
+			http.StatusOK,
+		},
+		{
+			"should not accept ld+json without the activitystreams profile",
+			"GET",
+			nil,
+			[]string{"application/ld+json"},
+			http.StatusUnsupportedMediaType,
+		},
+		{
+			"should not accept ld+json with another profile",
+			"GET",
+			nil,
+			[]string{"application/ld+json; profile=\"https://www.w3.org/ns/activitystreams-other\""},
+			http.StatusUnsupportedMediaType,
+		},
+		{
+			"should not accept activitypub types with zero quality",
+			"GET",
+			nil,
+			[]string{"application/activity+json;q=0"},
+			http.StatusUnsupportedMediaType,
+		},
+		{
+			"should not accept requests with no accept types",
+			"GET",
+			[]string{"application/activity+json"},
 			[]string{""},
 			http.StatusUnsupportedMediaType,
 		},
 		{
-			"should not accept requests with wrong accept types and wrong content type",
+			"should not accept requests with wrong accept types",
+			"GET",
 			[]string{"application/json"},
 			[]string{"application/json"},
 			http.StatusUnsupportedMediaType,
 		},
 		{
-			"should accept requests with activitypub content type",
+			"should accept posts with activitypub content type",
+			"POST",
 			[]string{"application/activity+json"},
-			[]string{""},
+			nil,
 			http.StatusOK,
 		},
 		{
-			"should accept requests with activitypub content type and accept header",
-			[]string{"application/activity+json"},
-			[]string{"application/ld+json; profile=\"https://www.w3.org/ns/activitystreams\""},
+			"should accept posts with activitypub content type and charset",
+			"POST",
+			[]string{"application/activity+json; charset=utf-8"},
+			nil,
+			http.StatusOK,
+		},
+		{
+			"should accept posts with ld+json activitystreams content type",
+			"POST",
+			[]string{ldProfile},
+			nil,
 			http.StatusOK,
 		},
+		{
+			"should not accept posts with plain json content type",
+			"POST",
+			[]string{"application/json"},
+			[]string{"application/activity+json"},
+			http.StatusUnsupportedMediaType,
+		},
+		{
+			"should not accept posts with substring matching content type",
+			"POST",
+			[]string{"text/plain; note=application/activity+json"},
+			nil,
+			http.StatusUnsupportedMediaType,
+		},
 	}
 
 	for _, tt := range tests {
@@ -70,9 +129,9 @@ func TestActivityPubHeaders(t *testing.T) {
 
 			r := chi.NewRouter()
 			r.Use(ActivityPubHeaders)
-			r.Get("/", func(w http.ResponseWriter, r *http.Request) {})
+			r.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {})
 
-			req, _ := http.NewRequest("GET", "/", nil)
+			req, _ := http.NewRequest(tt.method, "/", nil)
 			req.Header["Content-Type"] = tt.inputContentType
 			req.Header["Accept"] = tt.inputAccept
 
@@ -82,6 +141,10 @@ func TestActivityPubHeaders(t *testing.T) {
 			if res.StatusCode != tt.want {
 				t.Errorf("response is incorrect, got %d, want %d", recorder.Code, tt.want)
 			}
+
+			if tt.want == http.StatusOK && res.Header.Get("Content-Type") != ActivityPubContentType {
+				t.Errorf("expected content type %s got %q", ActivityPubContentType, res.Header.Get("Content-Type"))
+			}
 		})
 	}
 }
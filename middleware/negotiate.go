@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+// ActivityPubOrHTML is a middleware which content negotiates between an
+// ActivityPub handler and an HTML handler. Requests asking for ActivityPub
+// go through ActivityPubHeaders to next, browsers asking for HTML are served
+// by html, and anything else is handled by ActivityPubHeaders
+func ActivityPubOrHTML(html http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		ap := ActivityPubHeaders(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept")
+			if !acceptsActivityPub(r) && acceptsHTML(r) {
+				html.ServeHTTP(w, r)
+				return
+			}
+			ap.ServeHTTP(w, r)
+		})
+	}
+}
+
+func acceptsHTML(r *http.Request) bool {
+	for _, accepted := range acceptedTypes(r) {
+		if accepted.mediaType == "text/html" {
+			return true
+		}
+	}
+	return false
+}
+
+// Preferred returns the media type out of offered which the Accept headers
+// of r give the highest weight, using the most specific match for each
+// offer. Ties go to the first offer, which is also returned when r has no
+// Accept header. It returns an empty string when no offer is acceptable
+func Preferred(r *http.Request, offered ...string) string {
+	if len(offered) == 0 {
+		return ""
+	}
+	if len(r.Header["Accept"]) == 0 {
+		return offered[0]
+	}
+
+	accepted := parseAccept(r)
+	best, bestWeight := "", 0.0
+	for _, offer := range offered {
+		weight := acceptWeight(accepted, offer)
+		if weight > bestWeight {
+			best, bestWeight = offer, weight
+		}
+	}
+	return best
+}
+
+// acceptWeight returns the weight of the accepted media type matching
+// mediaType most specifically, where an exact match beats type/* and type/*
+// beats */*, so a type excluded with q=0 stays excluded under a wildcard.
+// It returns 0 when mediaType is not accepted
+func acceptWeight(accepted []acceptedType, mediaType string) float64 {
+	mainType, _, _ := strings.Cut(mediaType, "/")
+	weight, specificity := 0.0, 0
+	for _, a := range accepted {
+		matched := 0
+		switch a.mediaType {
+		case mediaType:
+			matched = 3
+		case mainType + "/*":
+			matched = 2
+		case "*/*":
+			matched = 1
+		}
+		if matched > specificity {
+			weight, specificity = a.weight, matched
+		}
+	}
+	return weight
+}
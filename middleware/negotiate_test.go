@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+)
+
+func TestActivityPubOrHTML(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		name   string
+		accept string
+		want   string
+		status int
+	}{
+		{"browser gets html", "text/html,application/xhtml+xml,*/*;q=0.8", "html", http.StatusOK},
+		{"activitypub client gets json", "application/activity+json", "ap", http.StatusOK},
+		{"json-ld client gets json", "application/ld+json; profile=\"https://www.w3.org/ns/activitystreams\"", "ap", http.StatusOK},
+		{"other clients are rejected", "application/json", "", http.StatusUnsupportedMediaType},
+	}
+
+	for _, tt := range tests {
+		var tt = tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			html := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("html"))
+			})
+
+			r := chi.NewRouter()
+			r.With(ActivityPubOrHTML(html)).Get("/", func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("ap"))
+			})
+
+			req, _ := http.NewRequest("GET", "/", nil)
+			req.Header.Set("Accept", tt.accept)
+			recorder := httptest.NewRecorder()
+			r.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.status {
+				t.Fatalf("expected %d got %d", tt.status, recorder.Code)
+			}
+
+			if recorder.Body.String() != tt.want {
+				t.Errorf("expected %q handler got %q", tt.want, recorder.Body.String())
+			}
+
+			if recorder.Header().Get("Vary") != "Accept" {
+				t.Errorf("expected Vary: Accept got %q", recorder.Header().Get("Vary"))
+			}
+		})
+	}
+}
+
+func TestPreferred(t *testing.T) {
+	t.Parallel()
+
+	offered := []string{"application/xrd+xml", "application/json"}
+	var tests = []struct {
+		name   string
+		accept []string
+		want   string
+	}{
+		{"no accept header", nil, "application/xrd+xml"},
+		{"exact match", []string{"application/json"}, "application/json"},
+		{"higher weight wins", []string{"application/xrd+xml;q=0.1, application/json"}, "application/json"},
+		{"ties go to the first offer", []string{"application/json, application/xrd+xml"}, "application/xrd+xml"},
+		{"wildcard", []string{"*/*"}, "application/xrd+xml"},
+		{"specific match beats wildcard", []string{"application/*;q=0.5, application/json;q=0.2"}, "application/xrd+xml"},
+		{"excluded type", []string{"application/xrd+xml;q=0, */*"}, "application/json"},
+		{"nothing acceptable", []string{"text/html"}, ""},
+		{"json substring is not json", []string{"application/jsonx"}, ""},
+	}
+
+	for _, tt := range tests {
+		var tt = tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			req, _ := http.NewRequest("GET", "/", nil)
+			for _, accept := range tt.accept {
+				req.Header.Add("Accept", accept)
+			}
+
+			if got := Preferred(req, offered...); got != tt.want {
+				t.Errorf("expected %q got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -8,6 +8,15 @@ type Activity struct {
 	// We need to be able to distinguish between omitted IDs and blank IDs
 	ID *string `json:"@id,omitempty"`
 
+	// Addressing is used to only relay activities addressed to the public
+	To       interface{} `json:"https://www.w3.org/ns/activitystreams#to,omitempty"`
+	CC       interface{} `json:"https://www.w3.org/ns/activitystreams#cc,omitempty"`
+	Audience interface{} `json:"https://www.w3.org/ns/activitystreams#audience,omitempty"`
+
+	// AttributedTo is used to check embedded objects come from the
+	// origin of the activity
+	AttributedTo interface{} `json:"https://www.w3.org/ns/activitystreams#attributedTo,omitempty"`
+
 	// We don't care about the following fields so they are omittable and
 	// we simply pass them on
 	Actor      interface{} `json:"https://www.w3.org/ns/activitystreams#actor,omitempty"`
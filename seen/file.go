@@ -0,0 +1,228 @@
+package seen
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// MaxIDLength is the longest ID a FileSet persists. Longer IDs and IDs
+// holding control characters are only remembered in memory, so they can
+// neither break the file on load nor forge entries
+const MaxIDLength = 4096
+
+// maxLineLength is the longest line load reads, the ID plus its timestamp
+const maxLineLength = MaxIDLength + 32
+
+// compactSlack keeps small sets from being compacted on every write
+const compactSlack = 1000
+
+// FileSet is a seen-set persisted to an append-only file, so redeliveries
+// are still recognized after a restart. Each line holds the Unix time an
+// ID was seen and the ID. The file is rewritten without expired IDs when
+// it is opened and whenever it grows to twice the live IDs
+type FileSet struct {
+	mem  *MemorySet
+	path string
+
+	mu    sync.Mutex
+	file  *os.File
+	lines int
+}
+
+// OpenFileSet opens or creates the seen-set at path remembering IDs for window
+func OpenFileSet(path string, window time.Duration, now time.Time) (*FileSet, error) {
+	s := &FileSet{
+		mem:  NewMemorySet(window),
+		path: path,
+	}
+
+	f, err := os.Open(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("could not open seen-set %s: %w", path, err)
+	}
+	if err == nil {
+		err = s.load(f, now)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = s.compact()
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// load reads the entries of f which are still within the window
+func (s *FileSet) load(r io.Reader, now time.Time) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 4096), maxLineLength)
+	scanner.Split(scanShortLines(maxLineLength))
+	for scanner.Scan() {
+		line := scanner.Text()
+		sep := strings.IndexByte(line, '\t')
+		if sep < 0 {
+			continue
+		}
+
+		if line[:sep] == "-" {
+			delete(s.mem.ids, line[sep+1:])
+			continue
+		}
+
+		unix, err := strconv.ParseInt(line[:sep], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		at := time.Unix(unix, 0)
+		if storable(line[sep+1:]) && now.Sub(at) < s.mem.window {
+			s.mem.ids[line[sep+1:]] = at
+		}
+	}
+
+	err := scanner.Err()
+	if err != nil {
+		return fmt.Errorf("could not read seen-set %s: %w", s.path, err)
+	}
+	return nil
+}
+
+// Add records id as seen at now, and reports false when it was already
+// seen within the window
+func (s *FileSet) Add(id string, now time.Time) bool {
+	if !storable(id) {
+		return s.mem.Add(id, now)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.mem.Add(id, now) {
+		return false
+	}
+
+	s.appendLine(strconv.FormatInt(now.Unix(), 10), id)
+	return true
+}
+
+// Remove forgets id
+func (s *FileSet) Remove(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.mem.Remove(id)
+	if storable(id) {
+		s.appendLine("-", id)
+	}
+}
+
+// Len returns the number of IDs remembered
+func (s *FileSet) Len() int {
+	return s.mem.Len()
+}
+
+// Close closes the underlying file
+func (s *FileSet) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
+
+// storable reports whether id can be written to the file
+func storable(id string) bool {
+	return len(id) <= MaxIDLength && strings.IndexFunc(id, unicode.IsControl) < 0
+}
+
+// scanShortLines splits lines like bufio.ScanLines, but skips lines longer
+// than max instead of failing the scan
+func scanShortLines(max int) bufio.SplitFunc {
+	skipping := false
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		newline := bytes.IndexByte(data, '\n')
+		if skipping {
+			if newline < 0 {
+				return len(data), nil, nil
+			}
+			skipping = false
+			return newline + 1, nil, nil
+		}
+
+		if newline < 0 && len(data) >= max && !atEOF {
+			skipping = true
+			return len(data), nil, nil
+		}
+		return bufio.ScanLines(data, atEOF)
+	}
+}
+
+// appendLine appends an entry and compacts the file once it holds twice
+// as many lines as there are live IDs. Callers must hold mu
+func (s *FileSet) appendLine(field, id string) {
+	_, err := fmt.Fprintf(s.file, "%s\t%s\n", field, id)
+	if err != nil {
+		log.Printf("could not write seen-set %s: %v\n", s.path, err)
+		return
+	}
+
+	s.lines++
+	if s.lines > 2*s.mem.Len()+compactSlack {
+		err = s.compact()
+		if err != nil {
+			log.Printf("could not compact seen-set: %v\n", err)
+		}
+	}
+}
+
+// compact rewrites the file with only the live IDs. Callers must hold mu
+// or have exclusive access
+func (s *FileSet) compact() error {
+	tmpPath := s.path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("could not compact seen-set %s: %w", s.path, err)
+	}
+
+	w := bufio.NewWriter(tmp)
+	s.mem.Lock()
+	for id, at := range s.mem.ids {
+		fmt.Fprintf(w, "%d\t%s\n", at.Unix(), id)
+	}
+	s.lines = len(s.mem.ids)
+	s.mem.Unlock()
+
+	err = w.Flush()
+	if err == nil {
+		err = tmp.Close()
+	} else {
+		tmp.Close()
+	}
+	if err != nil {
+		return fmt.Errorf("could not compact seen-set %s: %w", s.path, err)
+	}
+
+	err = os.Rename(tmpPath, s.path)
+	if err != nil {
+		return fmt.Errorf("could not compact seen-set %s: %w", s.path, err)
+	}
+
+	if s.file != nil {
+		s.file.Close()
+	}
+	s.file, err = os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("could not open seen-set %s: %w", s.path, err)
+	}
+	return nil
+}
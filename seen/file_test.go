@@ -0,0 +1,117 @@
+package seen
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileSetPersists(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "seen")
+	if err != nil {
+		t.Fatalf("could not create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "seen.log")
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	s, err := OpenFileSet(path, time.Hour, start)
+	if err != nil {
+		t.Fatalf("could not open seen-set: %v", err)
+	}
+
+	s.Add("https://example.org/old", start)
+	s.Add("https://example.org/1", start.Add(30*time.Minute))
+	s.Add("https://example.org/removed", start.Add(30*time.Minute))
+	s.Remove("https://example.org/removed")
+	s.Close()
+
+	reopened, err := OpenFileSet(path, time.Hour, start.Add(61*time.Minute))
+	if err != nil {
+		t.Fatalf("could not reopen seen-set: %v", err)
+	}
+	defer reopened.Close()
+
+	var tests = []struct {
+		id    string
+		added bool
+	}{
+		{"https://example.org/1", false},
+		{"https://example.org/old", true},
+		{"https://example.org/removed", true},
+	}
+
+	for _, tt := range tests {
+		if added := reopened.Add(tt.id, start.Add(62*time.Minute)); added != tt.added {
+			t.Errorf("%s: expected added %v got %v", tt.id, tt.added, added)
+		}
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("could not read seen-set: %v", err)
+	}
+	if strings.Count(string(b), "\n") != 3 {
+		t.Errorf("expected the reopened file to be compacted, got:\n%s", b)
+	}
+}
+
+func TestFileSetUnstorableIDs(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "seen")
+	if err != nil {
+		t.Fatalf("could not create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "seen.log")
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	long := "https://example.org/" + strings.Repeat("a", 100000)
+	content := "1704067200\t" + long + "\n1704067200\thttps://example.org/1\n"
+	err = ioutil.WriteFile(path, []byte(content), 0600)
+	if err != nil {
+		t.Fatalf("could not write seen-set: %v", err)
+	}
+
+	s, err := OpenFileSet(path, time.Hour, start)
+	if err != nil {
+		t.Fatalf("could not open seen-set with a long line: %v", err)
+	}
+	if s.Add("https://example.org/1", start) {
+		t.Errorf("expected the entry after a long line to be loaded")
+	}
+
+	forged := "https://example.org/2\n-\thttps://example.org/1"
+	if !s.Add(forged, start) || s.Add(forged, start) {
+		t.Errorf("expected an ID with control characters to be remembered in memory")
+	}
+	if !s.Add(long, start) || s.Add(long, start) {
+		t.Errorf("expected a long ID to be remembered in memory")
+	}
+	s.Close()
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("could not read seen-set: %v", err)
+	}
+	if strings.Contains(string(b), "example.org/2") || strings.Contains(string(b), "aaaa") {
+		t.Errorf("expected unstorable IDs not to be written, got:\n%s", b)
+	}
+
+	reopened, err := OpenFileSet(path, time.Hour, start)
+	if err != nil {
+		t.Fatalf("could not reopen seen-set: %v", err)
+	}
+	defer reopened.Close()
+	if reopened.Add("https://example.org/1", start) {
+		t.Errorf("expected a forged removal not to forget an ID")
+	}
+}
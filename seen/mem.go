@@ -0,0 +1,69 @@
+package seen
+
+import (
+	"sync"
+	"time"
+)
+
+// MemorySet is an in-memory seen-set
+type MemorySet struct {
+	window time.Duration
+	ids    map[string]time.Time
+	pruned time.Time
+	sync.Mutex
+}
+
+// NewMemorySet returns a new MemorySet remembering IDs for window
+func NewMemorySet(window time.Duration) *MemorySet {
+	return &MemorySet{
+		window: window,
+		ids:    make(map[string]time.Time),
+	}
+}
+
+// Add records id as seen at now, and reports false when it was already
+// seen within the window
+func (m *MemorySet) Add(id string, now time.Time) bool {
+	m.Lock()
+	defer m.Unlock()
+	return m.add(id, now)
+}
+
+func (m *MemorySet) add(id string, now time.Time) bool {
+	if now.Sub(m.pruned) > m.window/2 {
+		m.prune(now)
+	}
+
+	at, ok := m.ids[id]
+	if ok && now.Sub(at) < m.window {
+		return false
+	}
+
+	m.ids[id] = now
+	return true
+}
+
+// Remove forgets id
+func (m *MemorySet) Remove(id string) {
+	m.Lock()
+	defer m.Unlock()
+	delete(m.ids, id)
+}
+
+// Len returns the number of IDs remembered, including expired IDs which
+// have not been pruned yet
+func (m *MemorySet) Len() int {
+	m.Lock()
+	defer m.Unlock()
+	return len(m.ids)
+}
+
+// prune drops IDs seen before the window. Callers must hold the lock
+func (m *MemorySet) prune(now time.Time) {
+	for id, at := range m.ids {
+		if now.Sub(at) >= m.window {
+			delete(m.ids, id)
+		}
+	}
+	m.pruned = now
+}
@@ -0,0 +1,41 @@
+package seen
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemorySet(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := NewMemorySet(time.Hour)
+
+	var tests = []struct {
+		name  string
+		id    string
+		at    time.Duration
+		added bool
+	}{
+		{"first delivery", "https://example.org/1", 0, true},
+		{"redelivery", "https://example.org/1", time.Minute, false},
+		{"other activity", "https://example.org/2", time.Minute, true},
+		{"redelivery at the end of the window", "https://example.org/1", 59 * time.Minute, false},
+		{"redelivery after the window", "https://example.org/1", 2 * time.Hour, true},
+	}
+
+	for _, tt := range tests {
+		if added := s.Add(tt.id, start.Add(tt.at)); added != tt.added {
+			t.Errorf("%s: expected added %v got %v", tt.name, tt.added, added)
+		}
+	}
+
+	s.Remove("https://example.org/1")
+	if !s.Add("https://example.org/1", start.Add(2*time.Hour)) {
+		t.Error("expected a removed ID to be accepted again")
+	}
+
+	if s.Len() != 1 {
+		t.Errorf("expected expired IDs to be pruned, got %d IDs", s.Len())
+	}
+}
@@ -0,0 +1,15 @@
+package seen
+
+import "time"
+
+// Set remembers the IDs of activities the relay received for a window of
+// time, so redelivered activities are only relayed once
+type Set interface {
+	// Add records id as seen at now, and reports false when it was
+	// already seen within the window
+	Add(id string, now time.Time) bool
+	// Remove forgets id, so it is accepted again when redelivered
+	Remove(id string)
+	// Len returns the number of IDs remembered
+	Len() int
+}
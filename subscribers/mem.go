@@ -0,0 +1,70 @@
+package subscribers
+
+import (
+	"sort"
+	"sync"
+)
+
+// MemoryRegistry is an in-memory subscriber registry
+type MemoryRegistry struct {
+	subs map[string]Subscriber
+	sync.RWMutex
+}
+
+// NewMemoryRegistry returns a new MemoryRegistry instance
+func NewMemoryRegistry() *MemoryRegistry {
+	return &MemoryRegistry{
+		subs: make(map[string]Subscriber),
+	}
+}
+
+// Add adds or replaces a subscriber
+func (m *MemoryRegistry) Add(sub Subscriber) bool {
+	m.Lock()
+	defer m.Unlock()
+	m.subs[sub.Actor] = sub
+	return true
+}
+
+// Remove removes the subscriber with the given actor IRI and reports
+// whether it was subscribed
+func (m *MemoryRegistry) Remove(actor string) bool {
+	m.Lock()
+	defer m.Unlock()
+	_, ok := m.subs[actor]
+	delete(m.subs, actor)
+	return ok
+}
+
+// Get returns the subscriber with the given actor IRI
+func (m *MemoryRegistry) Get(actor string) (Subscriber, bool) {
+	m.RLock()
+	defer m.RUnlock()
+	sub, ok := m.subs[actor]
+	return sub, ok
+}
+
+// List returns all subscribers ordered by subscription time
+func (m *MemoryRegistry) List() []Subscriber {
+	m.RLock()
+	subs := make([]Subscriber, 0, len(m.subs))
+	for _, sub := range m.subs {
+		subs = append(subs, sub)
+	}
+	m.RUnlock()
+
+	sort.Slice(subs, func(i, j int) bool {
+		if subs[i].Since.Equal(subs[j].Since) {
+			return subs[i].Actor < subs[j].Actor
+		}
+		return subs[i].Since.Before(subs[j].Since)
+	})
+	return subs
+}
+
+// Count returns the number of subscribers
+func (m *MemoryRegistry) Count() int {
+	m.RLock()
+	defer m.RUnlock()
+	return len(m.subs)
+}
@@ -0,0 +1,36 @@
+package subscribers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryRegistry(t *testing.T) {
+	t.Parallel()
+
+	registry := NewMemoryRegistry()
+	now := time.Now()
+	registry.Add(Subscriber{Actor: "https://b.example.org/actor", Since: now})
+	registry.Add(Subscriber{Actor: "https://a.example.org/actor", Since: now.Add(time.Minute)})
+
+	if registry.Count() != 2 {
+		t.Errorf("expected 2 subscribers got %d", registry.Count())
+	}
+
+	subs := registry.List()
+	if subs[0].Actor != "https://b.example.org/actor" {
+		t.Errorf("expected subscribers ordered by subscription time got %v", subs)
+	}
+
+	if !registry.Remove("https://b.example.org/actor") {
+		t.Errorf("expected subscriber to be removed")
+	}
+
+	if registry.Remove("https://b.example.org/actor") {
+		t.Errorf("expected removing an absent subscriber to fail")
+	}
+
+	if _, ok := registry.Get("https://a.example.org/actor"); !ok {
+		t.Errorf("expected remaining subscriber to be found")
+	}
+}
@@ -0,0 +1,29 @@
+package subscribers
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// Subscriber is a remote actor that follows the relay
+type Subscriber struct {
+	// Actor is the IRI of the subscribed actor
+	Actor string
+	// Inbox is the inbox activities are delivered to, and is empty
+	// when it is not known yet
+	Inbox string
+	Since time.Time
+	// Accept is the outbox ID of the Accept which answered the follow,
+	// which is retracted when the subscriber unfollows
+	Accept uuid.UUID
+}
+
+// Registry keeps track of the actors subscribed to the relay
+type Registry interface {
+	Add(sub Subscriber) bool
+	Remove(actor string) bool
+	Get(actor string) (Subscriber, bool)
+	List() []Subscriber
+	Count() int
+}
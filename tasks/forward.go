@@ -2,18 +2,30 @@ package tasks
 
 import (
 	"bytes"
+	"fmt"
 	"net/http"
 	"net/url"
 
+	"github.com/Koshroy/turnover/httpsig"
+	"github.com/Koshroy/turnover/middleware"
 	"github.com/gofrs/uuid"
 )
 
+// RequestSigner signs outgoing requests with a signature scheme
+type RequestSigner interface {
+	Sign(req *http.Request, body []byte, scheme httpsig.Scheme) error
+}
+
 // Forward is a task which forwards a message
 type Forward struct {
 	TaskID   uuid.UUID
 	Activity []byte
 	Target   url.URL
 	Client   *http.Client
+	// Signer signs the delivery, which is sent unsigned when Signer is nil
+	Signer RequestSigner
+	// Schemes remembers which signature scheme each host accepted
+	Schemes *httpsig.HostSchemes
 }
 
 // ID returns the ID of the Forward task
@@ -21,16 +33,76 @@ func (f *Forward) ID() uuid.UUID {
 	return f.TaskID
 }
 
-// Run forwards the Activity to the Target
+// Run forwards the Activity to the Target. Signed deliveries try RFC 9421
+// first and fall back to draft-cavage when the target answers 401, unless
+// the host is already known to accept one of the schemes
 func (f *Forward) Run() error {
-	reader := bytes.NewReader(f.Activity)
-	resp, err := f.Client.Post(f.Target.String(), "application/ld+json", reader)
+	if f.Signer == nil {
+		return f.post(nil)
+	}
+
+	schemes := []httpsig.Scheme{httpsig.SchemeRFC9421, httpsig.SchemeCavage}
+	if f.Schemes != nil {
+		known, ok := f.Schemes.Get(f.Target.Host)
+		if ok && known == httpsig.SchemeCavage {
+			schemes = []httpsig.Scheme{httpsig.SchemeCavage, httpsig.SchemeRFC9421}
+		}
+	}
+
+	var err error
+	for _, scheme := range schemes {
+		scheme := scheme
+		err = f.post(&scheme)
+		if err == nil {
+			if f.Schemes != nil {
+				f.Schemes.Set(f.Target.Host, scheme)
+			}
+			return nil
+		}
+
+		if _, unauthorized := err.(unauthorizedError); !unauthorized {
+			return err
+		}
+	}
+	return err
+}
+
+// unauthorizedError is returned when the target rejects the signature
+type unauthorizedError struct {
+	scheme httpsig.Scheme
+}
+
+func (e unauthorizedError) Error() string {
+	return fmt.Sprintf("delivery signed with %s was unauthorized", e.scheme)
+}
+
+func (f *Forward) post(scheme *httpsig.Scheme) error {
+	req, err := http.NewRequest("POST", f.Target.String(), bytes.NewReader(f.Activity))
 	if err != nil {
 		return err
 	}
-	if resp.StatusCode > 399 {
+	req.Header.Set("Content-Type", middleware.ActivityPubContentType)
+
+	if scheme != nil {
+		err = f.Signer.Sign(req, f.Activity, *scheme)
+		if err != nil {
+			return err
+		}
+	}
+
+	resp, err := f.Client.Do(req)
+	if err != nil {
 		return err
 	}
 	_ = resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized && scheme != nil {
+		return unauthorizedError{scheme: *scheme}
+	}
+
+	if resp.StatusCode > 399 {
+		return fmt.Errorf("delivery to %s failed with status %d", f.Target.String(), resp.StatusCode)
+	}
+
 	return nil
 }
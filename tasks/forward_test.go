@@ -5,10 +5,14 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"testing"
 	"time"
 
+	"github.com/Koshroy/turnover/httpsig"
+	"github.com/Koshroy/turnover/keystore"
+	"github.com/Koshroy/turnover/middleware"
 	"github.com/gofrs/uuid"
 )
 
@@ -22,7 +26,7 @@ func (m *mockTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 	if req.URL.Host != "www.example.org" ||
 		req.URL.Path != "/inbox" ||
 		req.Method != "POST" ||
-		req.Header.Get("Content-Type") != "application/ld+json" {
+		req.Header.Get("Content-Type") != "application/activity+json" {
 		return nil, fmt.Errorf("should not access URL other than blessed URL")
 	}
 
@@ -93,3 +97,114 @@ func TestForwardTask(t *testing.T) {
 		t.FailNow()
 	}
 }
+
+type knockTransport struct {
+	acceptCavageOnly bool
+	schemes          []httpsig.Scheme
+}
+
+// RoundTrip records the signature scheme of each request and answers 401
+// to RFC 9421 signatures when only cavage is accepted
+func (k *knockTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	scheme := httpsig.SchemeCavage
+	if req.Header.Get("Signature-Input") != "" {
+		scheme = httpsig.SchemeRFC9421
+	}
+	k.schemes = append(k.schemes, scheme)
+
+	status := http.StatusAccepted
+	if k.acceptCavageOnly && scheme == httpsig.SchemeRFC9421 {
+		status = http.StatusUnauthorized
+	}
+
+	return &http.Response{
+		Status:     http.StatusText(status),
+		StatusCode: status,
+		Request:    req,
+		Header:     make(http.Header),
+		Body:       ioutil.NopCloser(bytes.NewReader([]byte{})),
+	}, nil
+}
+
+func TestForwardDoubleKnock(t *testing.T) {
+	t.Parallel()
+
+	store := keystore.MockStore()
+	signer := httpsig.NewSigner(store, func(id string) string {
+		return "https://relay.example.com/actor#" + id
+	})
+	schemes := httpsig.NewHostSchemes()
+	transport := &knockTransport{acceptCavageOnly: true}
+	target := url.URL{Scheme: "https", Host: "www.example.org", Path: "/inbox"}
+
+	newTask := func() *Forward {
+		tID, err := uuid.NewV4()
+		if err != nil {
+			t.Fatalf("error creating taskID: %v", err)
+		}
+		return &Forward{
+			TaskID:   tID,
+			Activity: []byte(`{"key":"value"}`),
+			Target:   target,
+			Client:   &http.Client{Transport: transport},
+			Signer:   signer,
+			Schemes:  schemes,
+		}
+	}
+
+	err := newTask().Run()
+	if err != nil {
+		t.Fatalf("task failed to run, received error: %v", err)
+	}
+
+	expected := []httpsig.Scheme{httpsig.SchemeRFC9421, httpsig.SchemeCavage}
+	if len(transport.schemes) != 2 ||
+		transport.schemes[0] != expected[0] || transport.schemes[1] != expected[1] {
+		t.Errorf("expected RFC 9421 then cavage got %v", transport.schemes)
+	}
+
+	if scheme, _ := schemes.Get("www.example.org"); scheme != httpsig.SchemeCavage {
+		t.Errorf("expected cavage to be remembered for host got %s", scheme)
+	}
+
+	transport.schemes = nil
+	err = newTask().Run()
+	if err != nil {
+		t.Fatalf("task failed to run, received error: %v", err)
+	}
+
+	if len(transport.schemes) != 1 || transport.schemes[0] != httpsig.SchemeCavage {
+		t.Errorf("expected only cavage once remembered got %v", transport.schemes)
+	}
+}
+
+func TestForwardPassesActivityPubHeaders(t *testing.T) {
+	t.Parallel()
+
+	// a relay inbox refuses requests without an ActivityPub media type
+	server := httptest.NewServer(middleware.ActivityPubHeaders(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})))
+	defer server.Close()
+
+	target, err := url.Parse(server.URL + "/inbox")
+	if err != nil {
+		t.Fatalf("could not parse server URL: %v", err)
+	}
+
+	tID, err := NewTaskID()
+	if err != nil {
+		t.Fatalf("error creating taskID: %v", err)
+	}
+
+	task := &Forward{
+		TaskID:   tID,
+		Activity: []byte(`{"key":"value"}`),
+		Target:   *target,
+		Client:   server.Client(),
+	}
+	err = task.Run()
+	if err != nil {
+		t.Errorf("expected the delivery to pass the ActivityPub header checks got %v", err)
+	}
+}
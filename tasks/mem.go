@@ -6,9 +6,12 @@ import (
 	"sync"
 )
 
-// MemoryQueue represents a task queue in memory
+// MemoryQueue represents a task queue in memory. Waiting tasks are not
+// bounded, so enqueueing never blocks on the workers
 type MemoryQueue struct {
-	waiting chan uuid.UUID
+	waitingLock sync.Mutex
+	waitingCond *sync.Cond
+	waiting     []uuid.UUID
 
 	finishedLock sync.RWMutex
 	finished     map[uuid.UUID]bool
@@ -19,26 +22,39 @@ type MemoryQueue struct {
 
 // NewMemoryQueue returns a new memory queue
 func NewMemoryQueue() *MemoryQueue {
-	return &MemoryQueue{
-		waiting:  make(chan uuid.UUID, 1),
+	m := &MemoryQueue{
 		finished: make(map[uuid.UUID]bool),
 		progress: make(map[uuid.UUID]bool),
 	}
+	m.waitingCond = sync.NewCond(&m.waitingLock)
+	return m
 }
 
 // Enqueue enques a task
 func (m *MemoryQueue) Enqueue(taskID uuid.UUID) bool {
-	m.waiting <- taskID
+	m.waitingLock.Lock()
+	m.waiting = append(m.waiting, taskID)
+	m.waitingLock.Unlock()
+
+	m.waitingCond.Signal()
 	return true
 }
 
 // Working returns a uuid.UUID from the list of waiting tasks and sets
-// it into the working state
+// it into the working state, waiting for a task when there is none
 func (m *MemoryQueue) Working() uuid.UUID {
+	m.waitingLock.Lock()
+	for len(m.waiting) == 0 {
+		m.waitingCond.Wait()
+	}
+	tID := m.waiting[0]
+	m.waiting[0] = uuid.Nil
+	m.waiting = m.waiting[1:]
+	m.waitingLock.Unlock()
+
 	m.progressLock.Lock()
 	defer m.progressLock.Unlock()
 
-	tID := <-m.waiting
 	m.progress[tID] = true
 	return tID
 }
@@ -57,17 +73,17 @@ func (m *MemoryQueue) ListWorking() []uuid.UUID {
 
 // Finish marks a taskID as finished if it is in progress already
 func (m *MemoryQueue) Finish(taskID uuid.UUID) bool {
-	m.progressLock.RLock()
+	m.progressLock.Lock()
 	if _, ok := m.progress[taskID]; !ok {
-		m.progressLock.RUnlock()
+		m.progressLock.Unlock()
 		return false
 	}
-	m.progressLock.RUnlock()
+	delete(m.progress, taskID)
+	m.progressLock.Unlock()
 
 	m.finishedLock.Lock()
 	defer m.finishedLock.Unlock()
 
-	delete(m.progress, taskID)
 	m.finished[taskID] = true
 	return true
 }
@@ -84,6 +100,14 @@ func (m *MemoryQueue) ListFinished() []uuid.UUID {
 	return tasks
 }
 
+// Remove drops a finished task, so finished tasks do not pile up
+func (m *MemoryQueue) Remove(taskID uuid.UUID) {
+	m.finishedLock.Lock()
+	defer m.finishedLock.Unlock()
+
+	delete(m.finished, taskID)
+}
+
 // MemoryStorage is an in-memory task storer
 type MemoryStorage struct {
 	taskStorage map[uuid.UUID]Task
@@ -114,3 +138,11 @@ func (s *MemoryStorage) Put(task Task, taskID uuid.UUID) bool {
 	s.taskStorage[taskID] = task
 	return true
 }
+
+// Delete removes the task with the given taskID
+func (s *MemoryStorage) Delete(taskID uuid.UUID) {
+	s.Lock()
+	defer s.Unlock()
+
+	delete(s.taskStorage, taskID)
+}
@@ -162,3 +162,26 @@ func TestMemStorage(t *testing.T) {
 		t.FailNow()
 	}
 }
+
+func TestEnqueueDoesNotBlockMemQueue(t *testing.T) {
+	t.Parallel()
+
+	queue := NewMemoryQueue()
+	tIDs := make([]uuid.UUID, 0, 100)
+	for n := 0; n < 100; n++ {
+		tID, err := uuid.NewV4()
+		if err != nil {
+			t.Fatalf("error generating task id: %v", err)
+		}
+		if !queue.Enqueue(tID) {
+			t.Fatalf("could not enqueue task %d", n)
+		}
+		tIDs = append(tIDs, tID)
+	}
+
+	for _, tID := range tIDs {
+		if workingTID := queue.Working(); !uuidEqual(workingTID, tID) {
+			t.Fatalf("expected tasks in order, expected %s got %s", tID, workingTID)
+		}
+	}
+}
@@ -15,12 +15,14 @@ type Queuer interface {
 	ListWorking() []uuid.UUID
 	Finish(taskID uuid.UUID) bool
 	ListFinished() []uuid.UUID
+	Remove(taskID uuid.UUID)
 }
 
 // Storer can load and store task data
 type Storer interface {
 	Get(taskID uuid.UUID) (Task, bool)
 	Put(task Task, taskID uuid.UUID) bool
+	Delete(taskID uuid.UUID)
 }
 
 // NewTaskID creates a new TaskID
@@ -0,0 +1,55 @@
+package tasks
+
+import (
+	"log"
+
+	"github.com/gofrs/uuid"
+)
+
+// Worker runs tasks taken off a Queuer
+type Worker struct {
+	queuer Queuer
+	storer Storer
+}
+
+// NewWorker returns a new Worker
+func NewWorker(queuer Queuer, storer Storer) *Worker {
+	return &Worker{
+		queuer: queuer,
+		storer: storer,
+	}
+}
+
+// Run takes tasks off the queue and runs them. It does not return. Several
+// goroutines may run the same Worker, so one slow target does not hold up
+// every delivery
+func (w *Worker) Run() {
+	for {
+		w.RunOne()
+	}
+}
+
+// RunOne waits for a single task and runs it. The task is then marked as
+// finished and dropped from the queue and the storage
+func (w *Worker) RunOne() {
+	taskID := w.queuer.Working()
+	defer w.done(taskID)
+
+	task, ok := w.storer.Get(taskID)
+	if !ok {
+		log.Printf("could not find task %s\n", taskID)
+		return
+	}
+
+	err := task.Run()
+	if err != nil {
+		log.Printf("error running task %s: %v\n", taskID, err)
+	}
+}
+
+// done marks taskID as finished and forgets it
+func (w *Worker) done(taskID uuid.UUID) {
+	w.queuer.Finish(taskID)
+	w.queuer.Remove(taskID)
+	w.storer.Delete(taskID)
+}
@@ -0,0 +1,116 @@
+package tasks
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+func TestWorkerRunOne(t *testing.T) {
+	t.Parallel()
+
+	tID, err := NewTaskID()
+	if err != nil {
+		t.Errorf("error generating task id: %v", err)
+		t.FailNow()
+	}
+
+	queue := NewMemoryQueue()
+	storage := NewMemoryStorage()
+	task := &runTask{TaskID: tID}
+	storage.Put(task, tID)
+	queue.Enqueue(tID)
+
+	NewWorker(queue, storage).RunOne()
+
+	if !task.ran {
+		t.Errorf("expected task %s to run", tID)
+	}
+	if finished := queue.ListFinished(); len(finished) != 0 {
+		t.Errorf("expected finished tasks to be dropped got %v", finished)
+	}
+	if _, ok := storage.Get(tID); ok {
+		t.Errorf("expected task %s to be deleted from the storage", tID)
+	}
+}
+
+// runTask records whether it ran
+type runTask struct {
+	TaskID uuid.UUID
+	ran    bool
+}
+
+func (t *runTask) ID() uuid.UUID {
+	return t.TaskID
+}
+
+func (t *runTask) Run() error {
+	t.ran = true
+	return nil
+}
+
+// blockingTask runs until release is closed
+type blockingTask struct {
+	TaskID  uuid.UUID
+	release chan struct{}
+}
+
+func (t *blockingTask) ID() uuid.UUID {
+	return t.TaskID
+}
+
+func (t *blockingTask) Run() error {
+	<-t.release
+	return nil
+}
+
+// doneTask closes done when it runs
+type doneTask struct {
+	TaskID uuid.UUID
+	done   chan struct{}
+}
+
+func (t *doneTask) ID() uuid.UUID {
+	return t.TaskID
+}
+
+func (t *doneTask) Run() error {
+	close(t.done)
+	return nil
+}
+
+func TestWorkersRunAroundSlowTasks(t *testing.T) {
+	t.Parallel()
+
+	queue := NewMemoryQueue()
+	storage := NewMemoryStorage()
+
+	slowID, err := NewTaskID()
+	if err != nil {
+		t.Fatalf("error generating task id: %v", err)
+	}
+	slow := &blockingTask{TaskID: slowID, release: make(chan struct{})}
+	defer close(slow.release)
+	storage.Put(slow, slowID)
+	queue.Enqueue(slowID)
+
+	fastID, err := NewTaskID()
+	if err != nil {
+		t.Fatalf("error generating task id: %v", err)
+	}
+	fast := &doneTask{TaskID: fastID, done: make(chan struct{})}
+	storage.Put(fast, fastID)
+	queue.Enqueue(fastID)
+
+	worker := NewWorker(queue, storage)
+	for n := 0; n < 2; n++ {
+		go worker.Run()
+	}
+
+	select {
+	case <-fast.done:
+	case <-time.After(time.Second):
+		t.Error("expected a second worker to run the task queued behind a slow one")
+	}
+}